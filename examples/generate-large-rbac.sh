@@ -0,0 +1,36 @@
+#!/bin/bash
+
+# Writes a List of RBAC resources the size of a large cluster to stdout, for the benchmarks (see make bench): per
+# namespace 15 ServiceAccounts, 5 Roles and 25 RoleBindings (to the Roles or to one of 100 ClusterRoles), so that the
+# default of 200 namespaces yields 5000 RoleBindings.
+namespaces=${1:-200}
+
+echo '{"apiVersion": "v1", "kind": "List", "items": ['
+for c in $(seq 0 99); do
+	rules=$(printf '{"apiGroups": [""], "resources": ["res%d", "other%d"], "verbs": ["get", "list"]},' $c $c $c $c $c $c $c $c $c $c)
+	echo "{\"kind\": \"ClusterRole\", \"metadata\": {\"name\": \"cr-$c\"}, \"rules\": [${rules%,}]},"
+done
+for n in $(seq 0 $((namespaces - 1))); do
+	ns="ns-$n"
+	for s in $(seq 0 14); do
+		echo "{\"kind\": \"ServiceAccount\", \"metadata\": {\"name\": \"sa-$s\", \"namespace\": \"$ns\"}},"
+	done
+	for r in $(seq 0 4); do
+		rules='{"apiGroups": [""], "resources": ["pods", "secrets"], "verbs": ["get"]},
+			{"apiGroups": [""], "resources": ["configmaps"], "verbs": ["get", "list", "watch"]},
+			{"apiGroups": ["apps"], "resources": ["deployments"], "verbs": ["get", "update", "patch"]},
+			{"apiGroups": ["rbac.authorization.k8s.io"], "resources": ["rolebindings"], "verbs": ["create"]}'
+		echo "{\"kind\": \"Role\", \"metadata\": {\"name\": \"role-$r\", \"namespace\": \"$ns\"}, \"rules\": [$rules]},"
+	done
+	for b in $(seq 0 24); do
+		if [ $((b % 2)) -eq 1 ]; then
+			ref="{\"kind\": \"Role\", \"name\": \"role-$((b % 5))\"}"
+		else
+			ref="{\"kind\": \"ClusterRole\", \"name\": \"cr-$(((n * 25 + b) % 100))\"}"
+		fi
+		subjects="{\"kind\": \"ServiceAccount\", \"name\": \"sa-$((b % 15))\", \"namespace\": \"$ns\"}, {\"kind\": \"ServiceAccount\", \"name\": \"sa-$(((b + 7) % 15))\", \"namespace\": \"$ns\"}"
+		echo "{\"kind\": \"RoleBinding\", \"metadata\": {\"name\": \"rb-$b\", \"namespace\": \"$ns\"}, \"roleRef\": $ref, \"subjects\": [$subjects]},"
+	done
+done
+echo '{"kind": "ClusterRoleBinding", "metadata": {"name": "crb-0"}, "roleRef": {"kind": "ClusterRole", "name": "cr-0"}, "subjects": [{"kind": "Group", "name": "devs"}]}'
+echo ']}'
@@ -0,0 +1,9 @@
+#!/bin/bash
+# Stands in for kubectl when testing -contexts: "kubectl-from-files.sh --context NAME get ..." prints examples/NAME.json
+# instead of fetching the resources from a cluster, and fails like kubectl does for unknown contexts.
+set -e
+if [ "$1" != "--context" ] || [ ! -f "$(dirname "$0")/$2.json" ]; then
+	echo "error: context \"$2\" does not exist" >&2
+	exit 1
+fi
+cat "$(dirname "$0")/$2.json"
@@ -2,4 +2,7 @@ module github.com/mhausenblas/rback
 
 go 1.12
 
-require github.com/emicklei/dot v0.10.0
+require (
+	github.com/emicklei/dot v0.10.0
+	sigs.k8s.io/yaml v1.3.0
+)
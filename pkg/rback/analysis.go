@@ -0,0 +1,416 @@
+package rback
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// grantsWildcard returns true if the rule grants all verbs or all resources
+func (rule *Rule) grantsWildcard() bool {
+	return contains(rule.verbs, "*") || contains(rule.resources, "*")
+}
+
+// grantsWildcard returns true if any of the role's rules grants all verbs or all resources
+func (role *Role) grantsWildcard() bool {
+	for _, rule := range role.rules {
+		if rule.grantsWildcard() {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyGrants returns true if the rule grants none but the given verbs (e.g. only read-only ones)
+func (rule *Rule) onlyGrants(verbs []string) bool {
+	for _, verb := range rule.verbs {
+		if !contains(verbs, verb) {
+			return false
+		}
+	}
+	return true
+}
+
+// appliesToResourceName returns true if the rule grants access to resources of the given name, i.e. it either lists
+// the name in its resourceNames or isn't restricted to any resourceNames at all. Rules for nonResourceURLs never apply.
+func (rule *Rule) appliesToResourceName(name string) bool {
+	if len(rule.resources) == 0 {
+		return false
+	}
+	return len(rule.resourceNames) == 0 || contains(rule.resourceNames, name)
+}
+
+// finding is a grant of a potentially risky access rule to a subject, as discovered by one of the analysis passes
+type finding struct {
+	subject KindNamespacedName
+	binding NamespacedName // the namespace is "" for ClusterRoleBindings, i.e. the rule is granted cluster-wide
+	role    NamespacedName // the namespace is "" for ClusterRoles
+	rule    Rule
+}
+
+// findGrants returns a finding for each subject that is granted a rule matching the given predicate
+func (r *Rback) findGrants(matches func(rule Rule) bool) []finding {
+	return r.findSubjectGrants(func(binding Binding, subject KindNamespacedName, rule Rule) bool {
+		return matches(rule)
+	})
+}
+
+// findSubjectGrants is like findGrants, but the predicate can also take the binding and the subject into account
+func (r *Rback) findSubjectGrants(matches func(binding Binding, subject KindNamespacedName, rule Rule) bool) []finding {
+	findings := []finding{}
+	r.eachGrant(func(binding Binding, subject KindNamespacedName, rule Rule) {
+		if matches(binding, subject, rule) {
+			findings = append(findings, finding{subject, binding.NamespacedName, binding.role, rule})
+		}
+	})
+	sortFindings(findings)
+	return findings
+}
+
+// eachGrant calls grant for each rule granted to each subject by the bindings of the selected namespaces (and the
+// ClusterRoleBindings)
+func (r *Rback) eachGrant(grant func(binding Binding, subject KindNamespacedName, rule Rule)) {
+	for ns, bindings := range r.permissions.RoleBindings {
+		if ns != "" && !r.namespaceSelected(ns) {
+			continue
+		}
+		for _, binding := range bindings {
+			role, found := r.lookupRole(binding.role)
+			if !found {
+				continue
+			}
+			for _, rule := range role.rules {
+				for _, subject := range binding.subjects {
+					grant(binding, subject, rule)
+				}
+			}
+		}
+	}
+}
+
+// sortFindings sorts findings by their descriptions, which are only formatted once each (rather than for every
+// comparison, which dominated the run time on clusters with thousands of bindings)
+func sortFindings(findings []finding) {
+	type keyed struct {
+		key string
+		finding
+	}
+	sorted := make([]keyed, len(findings))
+	for i, f := range findings {
+		sorted[i] = keyed{f.String(), f}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].key < sorted[j].key
+	})
+	for i := range sorted {
+		findings[i] = sorted[i].finding
+	}
+}
+
+func (f finding) String() string {
+	scope := "cluster-wide"
+	if f.binding.namespace != "" {
+		scope = "in namespace " + f.binding.namespace
+	}
+	return fmt.Sprintf("%s %s can %s %s via %s (%s)",
+		f.subject.kind, f.subject.qualifiedName(), f.rule.toHumanReadableString(), scope, f.roleDescription(), f.bindingDescription())
+}
+
+func (f finding) roleDescription() string {
+	if f.role.namespace == "" {
+		return "ClusterRole " + f.role.name
+	}
+	return "Role " + f.role.qualifiedName()
+}
+
+func (f finding) bindingDescription() string {
+	if f.binding.namespace == "" {
+		return "ClusterRoleBinding " + f.binding.name
+	}
+	return "RoleBinding " + f.binding.qualifiedName()
+}
+
+var readOnlyVerbs = []string{"get", "list", "watch"}
+
+// grantsUnscopedSecretReads returns true if the rule allows reading all secrets (i.e. it isn't restricted via resourceNames)
+func (rule *Rule) grantsUnscopedSecretReads() bool {
+	if len(rule.resourceNames) > 0 {
+		return false
+	}
+	if !contains(rule.resources, "secrets") && !contains(rule.resources, "*") {
+		return false
+	}
+	if len(rule.apiGroups) > 0 && !contains(rule.apiGroups, "") && !contains(rule.apiGroups, "*") {
+		return false // secrets are in the core API group
+	}
+	if contains(rule.verbs, "*") {
+		return true
+	}
+	for _, verb := range readOnlyVerbs {
+		if contains(rule.verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretWriteVerbs allow replacing or removing the contents of secrets, e.g. the tokens or credentials that workloads
+// rely on
+var secretWriteVerbs = []string{"create", "update", "patch", "delete", "deletecollection", "*"}
+
+// secretWritePart returns the verbs of the rule that allow writing secrets, if any (also if restricted via resourceNames)
+func (rule *Rule) secretWritePart() (Rule, bool) {
+	if !contains(rule.resources, "secrets") && !contains(rule.resources, "*") {
+		return Rule{}, false
+	}
+	if len(rule.apiGroups) > 0 && !contains(rule.apiGroups, "") && !contains(rule.apiGroups, "*") {
+		return Rule{}, false
+	}
+	part := *rule
+	part.verbs = []string{}
+	for _, verb := range rule.verbs {
+		if contains(secretWriteVerbs, verb) {
+			part.verbs = append(part.verbs, verb)
+		}
+	}
+	return part, len(part.verbs) > 0
+}
+
+// findSecretWriters finds the subjects that can write secrets. The findings only list the verbs that allow this.
+func (r *Rback) findSecretWriters() []finding {
+	findings := r.findGrants(func(rule Rule) bool {
+		_, writes := rule.secretWritePart()
+		return writes
+	})
+	for i := range findings {
+		findings[i].rule, _ = findings[i].rule.secretWritePart()
+	}
+	return findings
+}
+
+// findImpersonationGrants finds the subjects that can impersonate users, groups or ServiceAccounts
+func (r *Rback) findImpersonationGrants() []finding {
+	return r.findGrants(func(rule Rule) bool {
+		return len(rule.impersonatedKinds()) > 0
+	})
+}
+
+func (r *Rback) findSecretReaders() []finding {
+	return r.findGrants(func(rule Rule) bool {
+		return rule.grantsUnscopedSecretReads()
+	})
+}
+
+// escalationVerbs allow modifying RBAC resources, or creating bindings to (or roles with) permissions the subject doesn't
+// have itself (bind and escalate)
+var escalationVerbs = []string{"create", "update", "patch", "bind", "escalate", "*"}
+
+var rbacResources = []string{"roles", "clusterroles", "rolebindings", "clusterrolebindings", "*"}
+
+// escalationPart returns the verbs and resources of the rule that allow the subject to grant itself further permissions
+// by writing RBAC resources, if any
+func (rule *Rule) escalationPart() (Rule, bool) {
+	if len(rule.apiGroups) > 0 && !contains(rule.apiGroups, "rbac.authorization.k8s.io") && !contains(rule.apiGroups, "*") {
+		return Rule{}, false
+	}
+	part := *rule
+	part.verbs, part.resources = []string{}, []string{}
+	for _, verb := range rule.verbs {
+		if contains(escalationVerbs, verb) {
+			part.verbs = append(part.verbs, verb)
+		}
+	}
+	for _, resource := range rule.resources {
+		if contains(rbacResources, resource) {
+			part.resources = append(part.resources, resource)
+		}
+	}
+	return part, len(part.verbs) > 0 && len(part.resources) > 0
+}
+
+// findEscalationGrants finds the subjects that can write (Cluster)Roles or (Cluster)RoleBindings, and can thus grant
+// themselves further permissions. The findings only list the verbs and resources of each rule that allow this.
+func (r *Rback) findEscalationGrants() []finding {
+	findings := r.findGrants(func(rule Rule) bool {
+		_, escalates := rule.escalationPart()
+		return escalates
+	})
+	for i := range findings {
+		findings[i].rule, _ = findings[i].rule.escalationPart()
+	}
+	return findings
+}
+
+// findWildcardGrants finds the grants of rules that allow all verbs or all resources
+func (r *Rback) findWildcardGrants() []finding {
+	return r.findGrants(func(rule Rule) bool {
+		return rule.grantsWildcard()
+	})
+}
+
+// riskySubjects returns the subjects that are granted wildcard rules, rules that allow reading all secrets or rules
+// that allow writing RBAC resources. Unlike findGrants, it doesn't describe (and sort) each grant, which is costly for
+// thousands of bindings and not needed for the summary.
+func (r *Rback) riskySubjects() map[KindNamespacedName]bool {
+	risky := map[KindNamespacedName]bool{}
+	r.eachGrant(func(binding Binding, subject KindNamespacedName, rule Rule) {
+		_, escalates := rule.escalationPart()
+		if rule.grantsWildcard() || rule.grantsUnscopedSecretReads() || escalates {
+			risky[subject] = true
+		}
+	})
+	return risky
+}
+
+// summary describes the rendered graph in a single line, including the number of rendered subjects with risky grants.
+// ClusterRoles bound in several namespaces are rendered once per namespace, but only counted once.
+func (r *Rback) summary() string {
+	rendered := map[string]map[modelNode]bool{"ServiceAccount": {}, "Role": {}, "ClusterRole": {}}
+	for _, n := range r.model.nodes {
+		if byKind, ok := rendered[n.kind]; ok {
+			byKind[modelNode{kind: n.kind, namespace: iff(n.kind == "ClusterRole", "", n.namespace), label: n.label}] = true
+		}
+	}
+	subjects := map[KindNamespacedName]bool{}
+	for _, n := range r.model.nodes {
+		subjects[KindNamespacedName{n.kind, NamespacedName{n.namespace, n.label}}] = true
+	}
+	risky := map[KindNamespacedName]bool{}
+	for subject := range r.riskySubjects() {
+		if subjects[subject] {
+			risky[subject] = true
+		}
+	}
+	return fmt.Sprintf("Rendered %d ServiceAccounts, %d Roles and %d ClusterRoles, %d subjects flagged risky",
+		len(rendered["ServiceAccount"]), len(rendered["Role"]), len(rendered["ClusterRole"]), len(risky))
+}
+
+// findCrossNamespaceGrants finds the rules granted to ServiceAccounts outside of their own namespace, i.e. cluster-wide
+// via ClusterRoleBindings, or in other namespaces via RoleBindings there. These are potential paths for a compromised
+// workload to leak into other namespaces.
+func (r *Rback) findCrossNamespaceGrants() []finding {
+	return r.findSubjectGrants(func(binding Binding, subject KindNamespacedName, rule Rule) bool {
+		return subject.kind == "ServiceAccount" && subject.namespace != binding.namespace
+	})
+}
+
+// wellKnownClusterRoles are created by Kubernetes itself, so bindings referencing them are expected to work. If one of
+// them is missing, it was most likely deleted by accident.
+var wellKnownClusterRoles = []string{
+	"cluster-admin",
+	"admin",
+	"edit",
+	"view",
+	"system:auth-delegator",
+	"system:basic-user",
+	"system:discovery",
+	"system:public-info-viewer",
+	"system:heapster",
+	"system:kube-aggregator",
+	"system:kube-controller-manager",
+	"system:kube-dns",
+	"system:kube-scheduler",
+	"system:kubelet-api-admin",
+	"system:monitoring",
+	"system:node",
+	"system:node-bootstrapper",
+	"system:node-problem-detector",
+	"system:node-proxier",
+	"system:persistent-volume-provisioner",
+	"system:volume-scheduler",
+	"system:certificates.k8s.io:certificatesigningrequests:nodeclient",
+	"system:certificates.k8s.io:certificatesigningrequests:selfnodeclient",
+}
+
+// isDefaultBinding returns true for the ClusterRoleBindings that Kubernetes creates itself, which are named like the
+// well-known system: ClusterRole they bind (e.g. system:kube-dns, which binds the kube-dns ServiceAccount)
+func isDefaultBinding(binding Binding) bool {
+	return binding.namespace == "" && binding.role.namespace == "" && binding.name == binding.role.name &&
+		strings.HasPrefix(binding.name, "system:") && contains(wellKnownClusterRoles, binding.role.name)
+}
+
+// danglingBinding is a binding that references a role that doesn't exist
+type danglingBinding struct {
+	binding NamespacedName // the namespace is "" for ClusterRoleBindings
+	role    NamespacedName // the namespace is "" for ClusterRoles
+}
+
+func (d danglingBinding) String() string {
+	f := finding{binding: d.binding, role: d.role}
+	return fmt.Sprintf("%s references missing %s", f.bindingDescription(), f.roleDescription())
+}
+
+func (d danglingBinding) bindingDescription() string {
+	return finding{binding: d.binding}.bindingDescription()
+}
+
+// findDanglingBindings returns the (rendered or ignored) bindings that reference roles that don't exist, split into
+// those referencing one of the wellKnownClusterRoles and all others
+func (r *Rback) findDanglingBindings() (wellKnown, others []danglingBinding) {
+	wellKnown, others = []danglingBinding{}, []danglingBinding{}
+	for _, bindingsByNamespace := range []map[string]map[string]Binding{r.permissions.RoleBindings, r.permissions.IgnoredRoleBindings} {
+		for ns, bindings := range bindingsByNamespace {
+			if ns != "" && !r.namespaceSelected(ns) {
+				continue
+			}
+			for _, binding := range bindings {
+				if r.roleExists(binding.role) || r.permissions.IgnoredRoles[binding.role.namespace][binding.role.name] {
+					continue
+				}
+				dangling := danglingBinding{binding.NamespacedName, binding.role}
+				if binding.role.namespace == "" && contains(wellKnownClusterRoles, binding.role.name) {
+					wellKnown = append(wellKnown, dangling)
+				} else {
+					others = append(others, dangling)
+				}
+			}
+		}
+	}
+	for _, list := range [][]danglingBinding{wellKnown, others} {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].String() < list[j].String()
+		})
+	}
+	return wellKnown, others
+}
+
+// findOrphanServiceAccounts returns the ServiceAccounts that no binding (including ignored ones) grants a role to,
+// neither directly nor through their User name or the system:serviceaccounts groups (see grantees). Bindings to
+// system:authenticated don't count, as they grant their role to every User just as well.
+func (r *Rback) findOrphanServiceAccounts() []NamespacedName {
+	bound := map[KindNamespacedName]bool{}
+	for _, bindingsByNamespace := range []map[string]map[string]Binding{r.permissions.RoleBindings, r.permissions.IgnoredRoleBindings} {
+		for _, bindings := range bindingsByNamespace {
+			for _, binding := range bindings {
+				for _, subject := range binding.subjects {
+					bound[subject] = true
+				}
+			}
+		}
+	}
+	authenticated := KindNamespacedName{"Group", NamespacedName{"", "system:authenticated"}}
+	isBound := func(sa NamespacedName) bool {
+		for _, grantee := range (KindNamespacedName{"ServiceAccount", sa}).grantees() {
+			if grantee != authenticated && bound[grantee] {
+				return true
+			}
+		}
+		return false
+	}
+
+	orphans := []NamespacedName{}
+	for ns, sas := range r.permissions.ServiceAccounts {
+		if !r.namespaceSelected(ns) {
+			continue
+		}
+		for _, sa := range sas {
+			if !isBound(sa.NamespacedName) {
+				orphans = append(orphans, sa.NamespacedName)
+			}
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].qualifiedName() < orphans[j].qualifiedName()
+	})
+	return orphans
+}
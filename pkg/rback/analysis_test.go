@@ -0,0 +1,40 @@
+package rback
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrphanServiceAccountsIncludeGroupBindings(t *testing.T) {
+	binding := func(name, kind, subject string) string {
+		namespace := ""
+		if kind == "ServiceAccount" {
+			namespace = "app"
+		}
+		return `{"kind": "ClusterRoleBinding", "metadata": {"name": "` + name + `"}, "roleRef": {"kind": "ClusterRole", "name": "view"},
+			"subjects": [{"kind": "` + kind + `", "name": "` + subject + `", "namespace": "` + namespace + `"}]}`
+	}
+	sa := func(name string) string {
+		return `{"kind": "ServiceAccount", "metadata": {"name": "` + name + `", "namespace": "app"}}`
+	}
+	// without ignoring the prefix system:, which the User name of a ServiceAccount starts with
+	r := testRbackFromItems(t, testConfig(t, "-quiet", "-ignore-prefixes", "none"),
+		sa("direct"), binding("direct", "ServiceAccount", "direct"),
+		sa("user"), binding("user", "User", "system:serviceaccount:app:user"),
+		sa("orphan"), binding("authenticated", "Group", "system:authenticated"),
+	)
+	if orphans := r.findOrphanServiceAccounts(); !reflect.DeepEqual(orphans, []NamespacedName{{"app", "orphan"}}) {
+		t.Errorf("expected only app/orphan to be an orphan, got %v", orphans)
+	}
+
+	for _, group := range []string{"system:serviceaccounts", "system:serviceaccounts:app"} {
+		r := testRbackFromItems(t, testConfig(t, "-quiet"), sa("grouped"), binding("group", "Group", group))
+		if orphans := r.findOrphanServiceAccounts(); len(orphans) != 0 {
+			t.Errorf("expected the ServiceAccounts bound via group %s not to be orphans, got %v", group, orphans)
+		}
+	}
+	r = testRbackFromItems(t, testConfig(t, "-quiet"), sa("grouped"), binding("group", "Group", "system:serviceaccounts:other"))
+	if orphans := r.findOrphanServiceAccounts(); len(orphans) != 1 {
+		t.Errorf("expected app/grouped, bound via the group of another namespace, to be an orphan, got %v", orphans)
+	}
+}
@@ -0,0 +1,38 @@
+package rback
+
+import (
+	"fmt"
+	"strings"
+)
+
+// annotationSelector selects the objects annotated with key=value, e.g. by a CI system that stamps the RBAC objects
+// changed by a pull request, for -only-annotated
+type annotationSelector struct {
+	key, value string
+}
+
+func (s *annotationSelector) String() string {
+	if s.key == "" {
+		return ""
+	}
+	return s.key + "=" + s.value
+}
+
+func (s *annotationSelector) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value, but got %q", value)
+	}
+	s.key, s.value = parts[0], parts[1]
+	return nil
+}
+
+// annotated returns true if an object has the annotation given by -only-annotated (or if it isn't set)
+func (r *Rback) annotated(annotations map[string]string) bool {
+	selector := r.config.onlyAnnotated
+	if selector.key == "" {
+		return true
+	}
+	value, ok := annotations[selector.key]
+	return ok && value == selector.value
+}
@@ -0,0 +1,98 @@
+// Package rback renders Kubernetes RBAC resources (ServiceAccounts, (Cluster)Roles and (Cluster)RoleBindings) as
+// graphs. Main implements the rback command; other programs, e.g. a server rendering graphs on demand, can read the
+// resources with ParsePermissions and render them with RenderTo or RenderDOT. These only use the permissions and options
+// they're given, so they're safe to call concurrently, also with the same permissions.
+package rback
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Options selects what to render and how, like the command line of rback does. The zero value renders all resources as
+// a DOT graph, with rback's defaults.
+type Options struct {
+	// Format is the output format (see -format): dot (the default), graphml, html, json, markdown, metrics or yaml
+	Format string
+	// Namespaces limits the rendering to these namespaces (see -n); all namespaces are rendered if it's empty
+	Namespaces []string
+	// IgnoredPrefixes are the name prefixes of the (Cluster)Roles and (Cluster)RoleBindings to leave out (see
+	// -ignore-prefixes); nil leaves out those starting with "system:", like rback does by default, and an empty slice none
+	IgnoredPrefixes []string
+	// Selection selects the resources to render like the arguments of rback, e.g. []string{"sa", "my-sa"} or
+	// []string{"who-can", "get", "secrets"}; the commands that don't render anything, like orphan-sa, aren't supported
+	Selection []string
+	// Flags are any further flags of rback, e.g. []string{"-rules-style", "table"}. Those that read inputs or write
+	// files or reports (e.g. -f, -o or -report-only) aren't supported, and -v and -quiet have no effect.
+	Flags []string
+}
+
+// config converts the options into the command line of rback and parses it, so that they get the same defaults and
+// validation (but not the defaults from the environment, which are only meant for the command)
+func (o Options) config() (Config, error) {
+	args := append([]string{}, o.Flags...)
+	if o.Format != "" {
+		args = append(args, "-format", o.Format)
+	}
+	if len(o.Namespaces) > 0 {
+		args = append(args, "-n", strings.Join(o.Namespaces, ","))
+	}
+	if o.IgnoredPrefixes != nil {
+		prefixes := strings.Join(o.IgnoredPrefixes, ",")
+		if prefixes == "" {
+			prefixes = "none"
+		}
+		args = append(args, "-ignore-prefixes", prefixes)
+	}
+	fs := flag.NewFlagSet("rback", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard) // the error is returned instead
+	config, err := parseConfig(fs, append(args, o.Selection...), false)
+	if err != nil {
+		return config, err
+	}
+	if config.command != "" || len(config.inputFiles) > 0 || config.fromBundle != "" || len(config.contexts) > 0 || config.watch > 0 ||
+		config.outputPath != "" || config.splitBy != "" || config.bundle != "" || config.reportOnly || config.legendOnly {
+		return config, errors.New("the options only support rendering permissions, not commands, nor the flags that read inputs or write files or reports")
+	}
+	return config, nil
+}
+
+// ParsePermissions reads RBAC resources in JSON or YAML (e.g. as written by kubectl get -o json), which may be a List.
+// The permissions reflect the options that affect reading the resources (IgnoredPrefixes, -exclude-namespaces, the
+// whoami Selection and Format yaml, which keeps the resources as read), so they should be rendered with the same ones.
+func ParsePermissions(input io.Reader, options Options) (Permissions, error) {
+	config, err := options.config()
+	if err != nil {
+		return Permissions{}, err
+	}
+	r := Rback{config: config}
+	if err := r.parseRBAC(input); err != nil {
+		return Permissions{}, err
+	}
+	return r.permissions, nil
+}
+
+// RenderTo renders the permissions in the format given by the options (e.g. dot) and writes them to w, so that callers
+// can capture the output instead of it going to stdout
+func RenderTo(w io.Writer, permissions Permissions, options Options) error {
+	config, err := options.config()
+	if err != nil {
+		return err
+	}
+	r := Rback{config: config, permissions: permissions}
+	return r.writeOutput(w)
+}
+
+// RenderDOT renders the permissions as a DOT graph and returns it, regardless of the format given by the options
+func RenderDOT(permissions Permissions, options Options) (string, error) {
+	options.Format = formatDot
+	var dot bytes.Buffer
+	if err := RenderTo(&dot, permissions, options); err != nil {
+		return "", err
+	}
+	return dot.String(), nil
+}
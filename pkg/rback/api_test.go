@@ -0,0 +1,130 @@
+package rback_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mhausenblas/rback/pkg/rback"
+)
+
+// parsePermissions parses the given input file (relative to the package directory) like another program would
+func parsePermissions(t *testing.T, input string) rback.Permissions {
+	t.Helper()
+	file, err := os.Open(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	permissions, err := rback.ParsePermissions(file, rback.Options{})
+	if err != nil {
+		t.Fatalf("%s: %v", input, err)
+	}
+	return permissions
+}
+
+// TestRenderConcurrently renders distinct inputs in parallel, each with several options, using only the exported API.
+// It's meant to be run with -race (as by make test).
+func TestRenderConcurrently(t *testing.T) {
+	inputs := []string{
+		"../../examples/cross-namespace-roleref.json",
+		"../../examples/unusual-characters.json",
+		"../../examples/role-usage.json",
+		"../../examples/serviceaccount-groups.json",
+	}
+	options := []rback.Options{
+		{},
+		{Format: "json"},
+		{Flags: []string{"-rules-style", "table", "-effective-rules", "-show-permission-count"}},
+		{Selection: []string{"who-can", "get", "pods"}, Flags: []string{"-overview"}},
+	}
+	render := func(permissions rback.Permissions, options rback.Options) (string, error) {
+		var output bytes.Buffer
+		err := rback.RenderTo(&output, permissions, options)
+		return output.String(), err
+	}
+
+	permissions := make([]rback.Permissions, len(inputs))
+	expected := make([][]string, len(inputs)) // by input and options
+	for i, input := range inputs {
+		permissions[i] = parsePermissions(t, input)
+		for _, o := range options {
+			output, err := render(permissions[i], o)
+			if err != nil {
+				t.Fatalf("%s: %v", input, err)
+			}
+			expected[i] = append(expected[i], output)
+		}
+	}
+
+	const renderings = 64
+	var wg sync.WaitGroup
+	start := make(chan struct{}) // starts all renderings at once, so that they overlap as much as possible
+	errs := make(chan string, renderings)
+	for n := 0; n < renderings; n++ {
+		wg.Add(1)
+		go func(i, o int) {
+			defer wg.Done()
+			<-start
+			output, err := render(permissions[i], options[o])
+			if err != nil {
+				errs <- inputs[i] + ": " + err.Error()
+			} else if output != expected[i][o] {
+				errs <- inputs[i] + ": rendered differently than when rendered on its own"
+			}
+		}(n%len(inputs), n/len(inputs)%len(options))
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	permissions := parsePermissions(t, "../../examples/role-usage.json")
+	dot, err := rback.RenderDOT(permissions, rback.Options{Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dot, "digraph") {
+		t.Errorf("expected a DOT graph regardless of the format, got:\n%s", dot)
+	}
+
+	for _, options := range []rback.Options{
+		{Selection: []string{"orphan-sa"}},
+		{Flags: []string{"-o", "rbac.png"}},
+		{Flags: []string{"-report-only"}},
+		{Flags: []string{"-rules-style", "list"}},
+	} {
+		if _, err := rback.RenderDOT(permissions, options); err == nil {
+			t.Errorf("expected an error for %+v", options)
+		}
+	}
+}
+
+func TestJSONGraph(t *testing.T) {
+	permissions := parsePermissions(t, "../../examples/role-usage.json")
+	var output bytes.Buffer
+	if err := rback.RenderTo(&output, permissions, rback.Options{Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(&output)
+	decoder.DisallowUnknownFields() // the structs must cover everything that's written
+	var graph rback.JSONGraph
+	if err := decoder.Decode(&graph); err != nil {
+		t.Fatal(err)
+	}
+	if graph.SchemaVersion != rback.JSONSchemaVersion || len(graph.Nodes) == 0 || len(graph.Edges) == 0 {
+		t.Errorf("expected schema version %d with nodes and edges, got %+v", rback.JSONSchemaVersion, graph)
+	}
+	for _, node := range graph.Nodes {
+		if node.Kind == "Role" && len(node.Rules) == 0 {
+			t.Errorf("expected the rules of Role %s/%s", node.Namespace, node.Label)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package rback
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// the layout of the archives written and read by -bundle
+const (
+	bundleManifest  = "manifest.json"
+	bundleInputsDir = "inputs/"
+	bundleGraph     = "graph.dot"
+)
+
+// rawInput is an input as read, kept for writing it into a bundle
+type rawInput struct {
+	name string // the file name, or "stdin"
+	data bytes.Buffer
+}
+
+// bundleFile is a file in a bundle
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// bundleManifestData describes where and when the inputs of a bundle were fetched
+type bundleManifestData struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Created       time.Time `json:"created"`
+	Context       string    `json:"context,omitempty"`
+	Inputs        []string  `json:"inputs"`
+	Args          []string  `json:"args"` // the arguments rback was run with
+}
+
+// keepForBundle returns a reader of the input, keeping a copy of it for writing a bundle if -bundle is given
+func (r *Rback) keepForBundle(name string, reader io.Reader) io.Reader {
+	if r.config.bundle == "" {
+		return reader
+	}
+	input := &rawInput{name: name}
+	r.rawInputs = append(r.rawInputs, input)
+	return io.TeeReader(reader, &input.data)
+}
+
+// writeBundle writes a gzipped tar archive with the raw inputs, the graph rendered from them and a manifest, which can be
+// explored offline with different flags by passing it to -bundle again
+func (r *Rback) writeBundle(bundlePath string) error {
+	manifest := bundleManifestData{
+		SchemaVersion: JSONSchemaVersion,
+		Created:       r.config.now.UTC(),
+		Context:       r.config.bundleContext,
+		Inputs:        []string{},
+		Args:          os.Args[1:],
+	}
+	files := []bundleFile{}
+	for i, input := range r.rawInputs {
+		name := fmt.Sprintf("%s%02d-%s", bundleInputsDir, i+1, filepath.Base(input.name))
+		if !strings.HasSuffix(name, ".json") {
+			name += ".json"
+		}
+		manifest.Inputs = append(manifest.Inputs, name)
+		files = append(files, bundleFile{name, input.data.Bytes()})
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	files = append([]bundleFile{{bundleManifest, manifestData}}, files...)
+	files = append(files, bundleFile{bundleGraph, []byte(r.genGraph().String())})
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.data)), ModTime: manifest.Created}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(bundlePath, buf.Bytes(), 0644)
+}
+
+// parseBundle parses the inputs stored in a bundle written by writeBundle
+func (r *Rback) parseBundle(bundlePath string) error {
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("%s isn't a bundle: %v", bundlePath, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Can't read bundle %s: %v", bundlePath, err)
+		}
+		switch {
+		case header.Name == bundleManifest:
+			var manifest bundleManifestData
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return fmt.Errorf("Can't read the manifest of bundle %s: %v", bundlePath, err)
+			}
+			infof("Reading bundle %s created %s%s", bundlePath, manifest.Created.Format(time.RFC3339),
+				iff(manifest.Context != "", " from context "+manifest.Context, ""))
+		case path.Dir(header.Name)+"/" == bundleInputsDir:
+			if err := r.parseRBAC(tr); err != nil {
+				return fmt.Errorf("Can't parse RBAC resources from %s in bundle %s: %v", header.Name, bundlePath, err)
+			}
+			r.reportParsed(header.Name)
+		}
+	}
+}
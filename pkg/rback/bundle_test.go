@@ -0,0 +1,39 @@
+package rback
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundleIsWrittenAgainAndReadWithFromBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rback-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bundle := filepath.Join(dir, "rbac.tar.gz")
+
+	// writing a bundle that exists replaces it, rather than reading from it
+	for _, input := range []string{"../../examples/role-usage.json", "../../examples/serviceaccount-groups.json"} {
+		r := &Rback{config: testConfig(t, "-quiet", "-bundle", bundle, "-f", input)}
+		if err := r.parseInputs(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.writeBundle(bundle); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &Rback{config: testConfig(t, "-quiet", "-from-bundle", bundle)}
+	if err := r.parseInputs(); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := r.permissions.ServiceAccounts["b"]["default"]; !found {
+		t.Errorf("expected the resources of the last bundle written, got %v", r.permissions.ServiceAccounts)
+	}
+	if _, found := r.permissions.Roles["app"]["deployer"]; found {
+		t.Errorf("expected the resources of the first bundle written to be replaced, got %v", r.permissions.Roles)
+	}
+}
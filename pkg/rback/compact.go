@@ -0,0 +1,69 @@
+package rback
+
+import (
+	"sort"
+	"strings"
+)
+
+// compactRules merges rules that differ only in their verbs into a single rule with the union of the verbs, and then
+// rules that differ only in their resources (or nonResourceURLs) into a single rule with the union of those, for
+// -rules-format compact. Since a rule grants each of its verbs on each of its resources, the merged rules grant exactly
+// the same access. The order of the rules (by their first occurrence) is kept.
+func compactRules(rules []Rule) []Rule {
+	rules = mergeRules(rules, func(rule Rule) string {
+		return ruleKey(rule.apiGroups, rule.resources, rule.resourceNames, rule.nonResourceURLs)
+	}, func(merged *Rule, rule Rule) {
+		merged.verbs = appendMissing(merged.verbs, rule.verbs)
+	})
+	return mergeRules(rules, func(rule Rule) string {
+		// rules for resources and for nonResourceURLs are never merged with each other
+		return ruleKey(rule.verbs, rule.apiGroups, rule.resourceNames, []string{iff(len(rule.nonResourceURLs) > 0, "urls", "resources")})
+	}, func(merged *Rule, rule Rule) {
+		merged.resources = appendMissing(merged.resources, rule.resources)
+		merged.nonResourceURLs = appendMissing(merged.nonResourceURLs, rule.nonResourceURLs)
+	})
+}
+
+// mergeRules merges all rules with the same key into the first one of them
+func mergeRules(rules []Rule, key func(Rule) string, merge func(merged *Rule, rule Rule)) []Rule {
+	merged := []Rule{}
+	index := map[string]int{}
+	for _, rule := range rules {
+		k := key(rule)
+		if i, found := index[k]; found {
+			merge(&merged[i], rule)
+			continue
+		}
+		index[k] = len(merged)
+		// copy the lists, so that merging doesn't modify the rules of the role
+		merged = append(merged, Rule{
+			verbs:           append([]string{}, rule.verbs...),
+			resources:       append([]string{}, rule.resources...),
+			resourceNames:   append([]string{}, rule.resourceNames...),
+			nonResourceURLs: append([]string{}, rule.nonResourceURLs...),
+			apiGroups:       append([]string{}, rule.apiGroups...),
+		})
+	}
+	return merged
+}
+
+// ruleKey identifies the given lists of a rule, regardless of the order of their values
+func ruleKey(lists ...[]string) string {
+	parts := []string{}
+	for _, list := range lists {
+		sorted := append([]string{}, list...)
+		sort.Strings(sorted)
+		parts = append(parts, strings.Join(sorted, ","))
+	}
+	return strings.Join(parts, "|")
+}
+
+// appendMissing appends the values that aren't in the list yet
+func appendMissing(list, values []string) []string {
+	for _, value := range values {
+		if !contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,42 @@
+package rback
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRulesFormatCompact(t *testing.T) {
+	role := `{"kind": "Role", "metadata": {"name": "reader", "namespace": "app"},
+		"rules": [
+			{"apiGroups": [""], "resources": ["pods"], "verbs": ["get"]},
+			{"apiGroups": [""], "resources": ["services"], "verbs": ["get", "list"]},
+			{"apiGroups": [""], "resources": ["pods"], "verbs": ["list"]},
+			{"apiGroups": [""], "resources": ["secrets"], "resourceNames": ["tls"], "verbs": ["get"]}
+		]}`
+	binding := `{"kind": "RoleBinding", "metadata": {"name": "reader", "namespace": "app"},
+		"roleRef": {"kind": "Role", "name": "reader"},
+		"subjects": [{"kind": "ServiceAccount", "name": "worker", "namespace": "app"}]}`
+	tests := []struct {
+		rulesFormat string
+		expected    []string
+	}{
+		{rulesFormatFull, []string{`get pods`, `get,list services`, `list pods`, `get secrets "tls"`}},
+		// the verbs of the pods are merged first, and then the resources with the same verbs; resourceNames restrict a
+		// rule, so it's kept apart
+		{rulesFormatCompact, []string{`get,list pods,services`, `get secrets "tls"`}},
+	}
+	for _, test := range tests {
+		r := testRbackFromItems(t, testConfig(t, "-quiet", "-show-legend=false", "-rules-format", test.rulesFormat), role, binding)
+		r.genGraph()
+		rules := modelNodes(r, "Rules", "app")
+		if len(rules) != 1 {
+			t.Fatalf("expected one rules node in namespace app, got %v", rules)
+		}
+		if actual := strings.Split(strings.TrimSpace(rules[0].label), "\n"); !equalStrings(actual, test.expected) {
+			t.Errorf("-rules-format %s renders %q, expected %q", test.rulesFormat, actual, test.expected)
+		}
+	}
+	if config := testConfig(t, "-quiet"); config.rulesFormat != rulesFormatFull {
+		t.Errorf("-rules-format defaults to %q, expected %q", config.rulesFormat, rulesFormatFull)
+	}
+}
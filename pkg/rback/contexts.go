@@ -0,0 +1,138 @@
+package rback
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// contextResources are the resources fetched from each context given via -contexts, like the kubectl plugin does
+const contextResources = "sa,roles,rolebindings,clusterroles,clusterrolebindings"
+
+// kubectlGet runs kubectl get for the RBAC resources of all namespaces (and the namespaces themselves, if needed), with
+// the connection flags given to rback (e.g. -server) and the given kubectl flags (e.g. --context), and returns its output
+func (r *Rback) kubectlGet(flags ...string) ([]byte, error) {
+	flags = append(append([]string{}, r.config.kubectlFlags...), flags...)
+	resources := contextResources
+	if r.config.groupByLabel != "" {
+		resources += ",namespaces" // for the labels to group namespaces by
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(r.config.kubectl, append(flags, "get", resources, "--all-namespaces", "-o", "json")...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s get failed: %v: %s", r.config.kubectl, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// fetchContext runs kubectl get against the kubeconfig context and parses the RBAC resources it returns
+func (r *Rback) fetchContext(context string) (Permissions, error) {
+	var output []byte
+	var err error
+	timed("Fetching RBAC resources from context "+context, func() {
+		output, err = r.kubectlGet("--context", context)
+	})
+	if err != nil {
+		return Permissions{}, err
+	}
+	fetched := Rback{config: r.config}
+	if err := fetched.parseRBAC(bytes.NewReader(output)); err != nil {
+		return Permissions{}, err
+	}
+	return fetched.permissions, nil
+}
+
+// renderContexts renders the RBAC resources of each context given via -contexts into a cluster of its own, labeled with
+// the name of the context, all in one graph for comparing them. A context that can't be fetched is skipped with a
+// warning, unless none can.
+//
+// Each context is rendered by an instance of its own, exactly as if it was the only one, and spliced into the graph
+// with the title, caption and legend (see spliceContext), since the graph library can't nest graphs.
+func (r *Rback) renderContexts() (string, error) {
+	g := newGraph()
+	r.applyLayout(g)
+	r.renderTitleAndCaption(g)
+	r.renderLegend(g)
+	if r.config.overview {
+		g.Attr("compound", "true") // set by each context's overview, but only works for the whole graph
+	}
+	graph := g.String()
+
+	clusters := []string{}
+	for i, context := range r.config.contexts {
+		permissions, err := r.fetchContext(context)
+		if err != nil {
+			warnf("Skipping context %s: %v", context, err)
+			continue
+		}
+		config := r.config
+		config.contexts = nil
+		config.title, config.caption, config.showLegend = context, "", false
+		rendered := Rback{config: config, permissions: permissions}
+		cluster, err := spliceContext(rendered.genGraph().String(), fmt.Sprintf("context%d", i))
+		if err != nil {
+			return "", fmt.Errorf("Can't render context %s: %v", context, err)
+		}
+		clusters = append(clusters, cluster)
+		infof("Context %s: %s", context, rendered.summary())
+	}
+	if len(clusters) == 0 {
+		return "", fmt.Errorf("Can't fetch RBAC resources from any of the contexts %s", strings.Join(r.config.contexts, ", "))
+	}
+	end := strings.LastIndex(graph, "}")
+	return graph[:end] + strings.Join(clusters, "\n") + "\n" + graph[end:], nil
+}
+
+// generatedDOTID matches the IDs the graph library generates for nodes (n1) and subgraphs (s1, cluster_s1), and those
+// of the namespace clusters of -overview (cluster_namespace1)
+var generatedDOTID = regexp.MustCompile(`^(n|s|cluster_s|cluster_namespace)[0-9]+$`)
+
+// spliceContext turns a graph rendered for a single context into a cluster with the given ID, prefixing the IDs of its
+// nodes and subgraphs with it, so they don't collide with those of other contexts. Only unquoted IDs are node or
+// subgraph IDs, except for the quoted IDs of subgraphs and the references to clusters by lhead and ltail.
+func spliceContext(graph, id string) (string, error) {
+	tokens, err := tokenizeDOT(graph)
+	if err != nil {
+		return "", err
+	}
+	prefixed := func(value string) string {
+		if strings.HasPrefix(value, "cluster_") {
+			return "cluster_" + id + "_" + strings.TrimPrefix(value, "cluster_") // Graphviz only draws clusters named cluster*
+		}
+		return id + "_" + value
+	}
+	var b strings.Builder
+	last := 0
+	for i, token := range tokens {
+		value := token.value
+		if token.kind != dotID {
+			continue
+		}
+		quoted := strings.HasPrefix(value, `"`)
+		if quoted {
+			if i < 2 || !contains([]string{"ID", "lhead", "ltail"}, tokens[i-2].value) {
+				continue // e.g. a label that happens to look like an ID
+			}
+			value = strings.Trim(value, `"`)
+		}
+		if !generatedDOTID.MatchString(value) {
+			continue
+		}
+		b.WriteString(graph[last:token.offset])
+		b.WriteString(iff(quoted, `"`+prefixed(value)+`"`, prefixed(value)))
+		last = token.offset + len(token.value)
+	}
+	b.WriteString(graph[last:])
+
+	// the body of the graph, between "digraph  {" and the final "}", becomes the body of the cluster
+	spliced := b.String()
+	start, end := strings.Index(spliced, "{"), strings.LastIndex(spliced, "}")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("unexpected graph: %q", excerpt([]byte(spliced), 200))
+	}
+	return "subgraph cluster_" + id + " {" + spliced[start+1:end] + "}", nil
+}
@@ -0,0 +1,52 @@
+package rback
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKubectlConnectionFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rback-kubectl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// stands in for kubectl, recording its arguments (one per line) and printing an empty List
+	kubectl, args := filepath.Join(dir, "kubectl"), filepath.Join(dir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + args + "\necho '{\"kind\": \"List\", \"items\": []}'\n"
+	if err := ioutil.WriteFile(kubectl, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args     []string
+		fetch    func(r *Rback) error
+		expected string
+	}{
+		{
+			[]string{"-contexts", "dev", "-kubeconfig", "/my dir/config", "-server", "https://localhost:6443", "-insecure-skip-tls-verify"},
+			func(r *Rback) error { _, err := r.fetchContext("dev"); return err },
+			"--kubeconfig|/my dir/config|--server|https://localhost:6443|--insecure-skip-tls-verify|--context|dev|get|" + contextResources + "|--all-namespaces|-o|json",
+		},
+		{
+			[]string{"-watch", "10s", "-o", "rbac.dot", "-context", "dev", "-server", "https://localhost:6443"},
+			func(r *Rback) error { _, err := r.kubectlGet(); return err },
+			"--context|dev|--server|https://localhost:6443|get|" + contextResources + "|--all-namespaces|-o|json",
+		},
+	}
+	for _, test := range tests {
+		r := &Rback{config: testConfig(t, append([]string{"-quiet", "-kubectl", kubectl}, test.args...)...)}
+		if err := test.fetch(r); err != nil {
+			t.Fatal(err)
+		}
+		actual, err := ioutil.ReadFile(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if joined := strings.Join(strings.Split(strings.TrimSpace(string(actual)), "\n"), "|"); joined != test.expected {
+			t.Errorf("%v runs kubectl with %s, expected %s", test.args, joined, test.expected)
+		}
+	}
+}
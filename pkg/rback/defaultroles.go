@@ -0,0 +1,40 @@
+package rback
+
+import "github.com/emicklei/dot"
+
+// defaultRoleAggregations lists the well-known user-facing ClusterRoles that Kubernetes aggregates into each other out
+// of the box (via the aggregate-to-edit and aggregate-to-admin labels), i.e. all rules of view are also part of edit
+var defaultRoleAggregations = []struct{ from, to string }{
+	{"view", "edit"},
+	{"edit", "admin"},
+}
+
+// renderDefaultRoleHierarchy connects the rendered nodes of the default ClusterRoles according to
+// defaultRoleAggregations, for -show-default-role-hierarchy. Only nodes that are rendered for the same namespace (or
+// cluster-wide) are connected.
+func (r *Rback) renderDefaultRoleHierarchy(g *dot.Graph) {
+	namespaces := []string{""}
+	if !r.config.mergeClusterRoles {
+		for ns := range r.permissions.RoleBindings {
+			if ns != "" {
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+	for _, ns := range namespaces {
+		for _, aggregation := range defaultRoleAggregations {
+			fromID := r.roleNodeID(ns, NamespacedName{name: aggregation.from})
+			toID := r.roleNodeID(ns, NamespacedName{name: aggregation.to})
+			if !r.model.nodeIDs[fromID] || !r.model.nodeIDs[toID] {
+				continue
+			}
+			// looking up the existing nodes requires the (sub)graph they were rendered in
+			gns := g
+			if !r.config.mergeClusterRoles {
+				gns = r.newNamespaceSubgraph(g, ns)
+			}
+			newAggregationEdge(gns.Node(fromID), gns.Node(toID))
+			r.model.addEdge(fromID, toID)
+		}
+	}
+}
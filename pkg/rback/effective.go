@@ -0,0 +1,196 @@
+package rback
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/emicklei/dot"
+)
+
+// scopedRule is an access rule together with the namespace it applies in ("" means cluster-wide)
+type scopedRule struct {
+	namespace string
+	rule      Rule
+}
+
+func (r *Rback) resetEffectiveRules() {
+	r.effective = nil
+	r.permissionCounts = nil
+	r.bindingsByGrantee = nil
+}
+
+// effectiveRules returns the de-duplicated union of the rules granted to the subject through all of its bindings,
+// including those of the groups it's implicitly a member of (see grantees). Rules that are granted cluster-wide are not
+// repeated for individual namespaces.
+//
+// Since each subject may be rendered many times (e.g. in each namespace it's bound in), the bindings are indexed by
+// their subjects once, and the rules of each subject are only computed once.
+func (r *Rback) effectiveRules(subject KindNamespacedName) []scopedRule {
+	if rules, cached := r.effective[subject]; cached {
+		return rules
+	}
+	if r.effective == nil {
+		r.effective = map[KindNamespacedName][]scopedRule{}
+	}
+
+	granted := map[string]map[string]Rule{} // map[namespace]map[rule]Rule
+	for _, grantee := range subject.grantees() {
+		for _, binding := range r.bindingsOf(grantee) {
+			role, found := r.lookupRole(binding.role)
+			if !found {
+				continue
+			}
+			if granted[binding.namespace] == nil {
+				granted[binding.namespace] = make(map[string]Rule)
+			}
+			for _, rule := range r.shownRules(role.rules) {
+				granted[binding.namespace][rule.toHumanReadableString()] = rule
+			}
+		}
+	}
+
+	result := []scopedRule{}
+	for ns, rules := range granted {
+		for key, rule := range rules {
+			if _, grantedClusterWide := granted[""][key]; ns != "" && grantedClusterWide {
+				continue
+			}
+			result = append(result, scopedRule{ns, rule})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].namespace != result[j].namespace {
+			return result[i].namespace < result[j].namespace
+		}
+		return result[i].rule.toHumanReadableString() < result[j].rule.toHumanReadableString()
+	})
+	r.effective[subject] = result
+	return result
+}
+
+// permissionCount returns the number of distinct (verb, apiGroup, resource) triples granted to the subject in any
+// namespace, as a rough measure of how broad its access is. Non-resource URLs count as resources without an API group,
+// and wildcards are counted as a single verb, group or resource. Like the effective rules, it's computed once per subject.
+func (r *Rback) permissionCount(subject KindNamespacedName) int {
+	if count, cached := r.permissionCounts[subject]; cached {
+		return count
+	}
+	permissions := map[[3]string]bool{}
+	for _, sr := range r.effectiveRules(subject) {
+		for _, verb := range sr.rule.verbs {
+			for _, resource := range sr.rule.nonResourceURLs {
+				permissions[[3]string{verb, "", resource}] = true
+			}
+			for _, apiGroup := range sr.rule.apiGroups {
+				for _, resource := range sr.rule.resources {
+					permissions[[3]string{verb, apiGroup, resource}] = true
+				}
+			}
+		}
+	}
+	if r.permissionCounts == nil {
+		r.permissionCounts = map[KindNamespacedName]int{}
+	}
+	r.permissionCounts[subject] = len(permissions)
+	return len(permissions)
+}
+
+// bindingsOf returns the (non-ignored) bindings that name the subject explicitly, indexing all bindings by their
+// subjects the first time
+func (r *Rback) bindingsOf(subject KindNamespacedName) []Binding {
+	if r.bindingsByGrantee == nil {
+		r.bindingsByGrantee = map[KindNamespacedName][]Binding{}
+		for _, bindings := range r.permissions.RoleBindings {
+			for _, binding := range bindings {
+				// a binding that repeats a subject is indexed twice, which only repeats rules that are de-duplicated anyway
+				for _, s := range binding.subjects {
+					r.bindingsByGrantee[s] = append(r.bindingsByGrantee[s], binding)
+				}
+			}
+		}
+	}
+	return r.bindingsByGrantee[subject]
+}
+
+func (b *Binding) hasSubject(subject KindNamespacedName) bool {
+	for _, s := range b.subjects {
+		if s == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// grantsTo returns true if the binding grants its role to the subject, either directly or through the groups that
+// Kubernetes puts it into implicitly (see grantees)
+func (b *Binding) grantsTo(subject KindNamespacedName) bool {
+	for _, grantee := range subject.grantees() {
+		if b.hasSubject(grantee) {
+			return true
+		}
+	}
+	return false
+}
+
+// grantees returns the subjects whose bindings grant permissions to the subject: the subject itself, and the groups
+// Kubernetes puts it into implicitly, i.e. system:authenticated for Users and ServiceAccounts, and
+// system:serviceaccounts and system:serviceaccounts:NAMESPACE for ServiceAccounts, which are also bound as the User
+// system:serviceaccount:NAMESPACE:NAME. Other group memberships aren't part of the input.
+func (subject KindNamespacedName) grantees() []KindNamespacedName {
+	grantees := []KindNamespacedName{subject}
+	var id identity
+	switch subject.kind {
+	case "ServiceAccount":
+		id.user = serviceAccountUserPrefix + subject.namespace + ":" + subject.name
+		grantees = append(grantees, KindNamespacedName{"User", NamespacedName{"", id.user}})
+	case "User":
+		id.user = subject.name
+	default:
+		return grantees
+	}
+	if id.user != anonymousUser {
+		grantees = append(grantees, KindNamespacedName{"Group", NamespacedName{"", "system:authenticated"}})
+	}
+	if ns, name, ok := id.serviceAccount(); ok {
+		if subject.kind == "User" {
+			grantees = append(grantees, KindNamespacedName{"ServiceAccount", NamespacedName{ns, name}})
+		}
+		grantees = append(grantees,
+			KindNamespacedName{"Group", NamespacedName{"", serviceAccountsGroup}},
+			KindNamespacedName{"Group", NamespacedName{"", serviceAccountsGroup + ":" + ns}})
+	}
+	return grantees
+}
+
+func (r *Rback) newEffectiveRulesNode(gns *dot.Graph, subjectNode dot.Node, subject KindNamespacedName) {
+	rules := r.effectiveRules(subject)
+	if len(rules) == 0 {
+		return
+	}
+
+	var lines []string
+	scope := "-" // no namespace is named "-", so the first rule always starts a new section
+	for _, sr := range rules {
+		if sr.namespace != scope {
+			scope = sr.namespace
+			if scope == "" {
+				lines = append(lines, "Cluster-wide:")
+			} else {
+				lines = append(lines, "In namespace "+scope+":")
+			}
+		}
+		lines = append(lines, "  "+sr.rule.toHumanReadableString())
+	}
+
+	rulesText := ""
+	for _, line := range lines {
+		rulesText += regularLine(line)
+	}
+
+	rulesNode := newEffectiveRulesNode0(gns, subject.namespace, subject.name, rulesText)
+	newSubjectToEffectiveRulesEdge(subjectNode, rulesNode)
+
+	rulesNodeID := effectiveRulesNodeID(subject.namespace, subject.name)
+	r.model.addNode(rulesNodeID, "EffectiveRules", subject.namespace, strings.Join(lines, "\n"))
+	r.model.addEdge(subjectNodeID(subject.kind, subject.namespace, subject.name), rulesNodeID)
+}
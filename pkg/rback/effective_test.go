@@ -0,0 +1,118 @@
+package rback
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEffectiveRulesIncludeImplicitGroups(t *testing.T) {
+	r := testRback(t, testConfig(t, "-quiet"), "../../examples/serviceaccount-groups.json")
+	tests := []struct {
+		subject  KindNamespacedName
+		expected []string // the effective rules, as namespace: rule
+	}{
+		// system:authenticated
+		{KindNamespacedName{"ServiceAccount", NamespacedName{"a", "default"}}, []string{"a: get,list pods"}},
+		// system:serviceaccounts:b and system:authenticated
+		{KindNamespacedName{"ServiceAccount", NamespacedName{"b", "default"}}, []string{": * * (*)", "a: get,list pods"}},
+		{KindNamespacedName{"User", NamespacedName{"", "carol"}}, []string{"a: get,list pods"}},
+		{KindNamespacedName{"User", NamespacedName{"", anonymousUser}}, []string{}},
+		// groups aren't members of other groups
+		{KindNamespacedName{"Group", NamespacedName{"", "system:serviceaccounts:b"}}, []string{": * * (*)"}},
+	}
+	for _, test := range tests {
+		actual := []string{}
+		for _, sr := range r.effectiveRules(test.subject) {
+			actual = append(actual, sr.namespace+": "+sr.rule.toHumanReadableString())
+		}
+		if !equalStrings(actual, test.expected) {
+			t.Errorf("effective rules of %s %s: %q, expected %q", test.subject.kind, test.subject.qualifiedName(), actual, test.expected)
+		}
+	}
+	if count := r.permissionCount(KindNamespacedName{"ServiceAccount", NamespacedName{"b", "default"}}); count != 3 {
+		t.Errorf("permission count of ServiceAccount b/default: %d, expected 3", count)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGranteesMatchImplicitGroups(t *testing.T) {
+	subjects := []KindNamespacedName{
+		{"ServiceAccount", NamespacedName{"a", "default"}},
+		{"ServiceAccount", NamespacedName{"b", "default"}},
+		{"User", NamespacedName{"", "carol"}},
+		{"User", NamespacedName{"", anonymousUser}},
+		{"User", NamespacedName{"", "system:serviceaccount:a:default"}},
+		{"Group", NamespacedName{"", "system:serviceaccounts:a"}},
+		{"Group", NamespacedName{"", "oncall"}},
+	}
+	bindingSubjects := append(subjects,
+		KindNamespacedName{"Group", NamespacedName{"", "system:authenticated"}},
+		KindNamespacedName{"Group", NamespacedName{"", "system:unauthenticated"}},
+		KindNamespacedName{"Group", NamespacedName{"", "system:serviceaccounts"}},
+		KindNamespacedName{"Group", NamespacedName{"", "system:serviceaccounts:b"}},
+		KindNamespacedName{"User", NamespacedName{"", "system:serviceaccount:b:default"}},
+	)
+	for _, subject := range subjects {
+		// the identity the subject authenticates as, if any, which is what whoami checks
+		var id *identity
+		switch subject.kind {
+		case "ServiceAccount":
+			id = &identity{user: serviceAccountUserPrefix + subject.namespace + ":" + subject.name}
+		case "User":
+			id = &identity{user: subject.name}
+		}
+		for _, s := range bindingSubjects {
+			expected := s == subject || (id != nil && id.includes(s))
+			binding := Binding{subjects: []KindNamespacedName{s}}
+			if actual := binding.grantsTo(subject); actual != expected {
+				t.Errorf("a binding of %s %s grants to %s %s: %v, expected %v", s.kind, s.qualifiedName(), subject.kind, subject.qualifiedName(), actual, expected)
+			}
+		}
+	}
+}
+
+func TestEffectiveRulesAreComputedOnce(t *testing.T) {
+	r := testRback(t, testConfig(t, "-quiet", "-effective-rules", "-show-permission-count"), "../../examples/serviceaccount-groups.json")
+	graph := r.genGraph().String()
+	subject := KindNamespacedName{"ServiceAccount", NamespacedName{"b", "default"}}
+	cached, found := r.effective[subject]
+	if !found {
+		t.Fatalf("expected the effective rules of ServiceAccount b/default to be cached by rendering")
+	}
+	fresh := &Rback{config: r.config, permissions: r.permissions}
+	if expected := fresh.effectiveRules(subject); len(cached) != len(expected) || len(cached) != 2 {
+		t.Errorf("cached effective rules %v, expected %v", cached, expected)
+	}
+
+	if count, found := r.permissionCounts[subject]; !found || count != 3 {
+		t.Errorf("expected the permission count 3 of ServiceAccount b/default to be cached by rendering, got %d", count)
+	}
+	if !strings.Contains(graph, "[3 permissions]") {
+		t.Errorf("expected ServiceAccount b/default to be labeled with its 3 permissions, got:\n%s", graph)
+	}
+
+	// parsing more resources invalidates the cache
+	if err := r.parseRBAC(strings.NewReader(`{"kind": "RoleBinding", "metadata": {"name": "more", "namespace": "b"},
+		"roleRef": {"kind": "ClusterRole", "name": "pod-reader"},
+		"subjects": [{"kind": "ServiceAccount", "name": "default", "namespace": "b"}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if r.permissionCounts != nil {
+		t.Errorf("expected parsing to reset the permission counts, got %v", r.permissionCounts)
+	}
+	if rules := r.effectiveRules(subject); len(rules) != 3 {
+		t.Errorf("expected the rules of the added RoleBinding in namespace b, got %v", rules)
+	}
+
+}
@@ -0,0 +1,52 @@
+package rback
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix is the prefix of the environment variables that provide defaults for flags, e.g. RBACK_IGNORE_PREFIXES for
+// -ignore-prefixes
+const envPrefix = "RBACK_"
+
+// flagAliases maps flags to the flag they're an alias of, so that the environment variable of one doesn't override the
+// other when it's passed on the command line
+var flagAliases = map[string]string{"namespace": "n"}
+
+// envVarName returns the name of the environment variable for a flag
+func envVarName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// applyEnvDefaults sets each flag that wasn't passed on the command line from its environment variable, if that's set.
+// Hence explicit flags take precedence over environment variables, which take precedence over the built-in defaults.
+func applyEnvDefaults(flags *flag.FlagSet) error {
+	passed := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+		if alias, ok := flagAliases[f.Name]; ok {
+			passed[alias] = true
+		}
+	})
+	for alias, name := range flagAliases {
+		if passed[name] {
+			passed[alias] = true
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || passed[f.Name] || contains(hiddenFlags, f.Name) {
+			return
+		}
+		name := envVarName(f.Name)
+		if value, ok := os.LookupEnv(name); ok {
+			if setErr := flags.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("Invalid value %q of %s: %v", value, name, setErr)
+			}
+		}
+	})
+	return err
+}
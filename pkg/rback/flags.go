@@ -0,0 +1,426 @@
+package rback
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// flagValues holds the flags that are validated or converted (e.g. split, if they're comma-delimited lists) before
+// they're stored in the Config
+type flagValues struct {
+	inputFiles, contexts           string
+	context, kubeconfig, server    string
+	insecureSkipTLSVerify          bool
+	formatPassed                   bool // whether -format was given, rather than inferred from -o
+	markdownSections               string
+	namespaces, excludedNamespaces string
+	ignoredPrefixes, subjectKinds  string
+	readOnlyVerbs                  string
+	hiddenResources, hiddenVerbs   string
+	noRulesFor, focus              string
+	nodeLabelTemplate              string
+	suppressedCodes, riskPolicy    string
+	failOnRisky                    bool
+}
+
+// parseConfig registers rback's flags with fs, and parses and validates the arguments (and, with envDefaults, the
+// environment variables of the flags that aren't passed). The flags of each feature are registered by a register* function, and validated
+// and converted into the Config by the matching apply* function.
+func parseConfig(fs *flag.FlagSet, args []string, envDefaults bool) (Config, error) {
+	config := Config{now: time.Now()}
+	values := &flagValues{}
+	registerInputFlags(fs, &config, values)
+	registerOutputFlags(fs, &config, values)
+	registerSelectionFlags(fs, &config, values)
+	registerGraphFlags(fs, &config, values)
+	registerReportFlags(fs, &config, values)
+	registerLoggingFlags(fs, &config)
+	if err := fs.Parse(args); err != nil {
+		return config, err
+	}
+	positional, err := positionalArgs(fs)
+	if err != nil {
+		return config, err
+	}
+	if envDefaults {
+		if err := applyEnvDefaults(fs); err != nil {
+			return config, err
+		}
+	}
+	fs.Visit(func(f *flag.Flag) { values.formatPassed = values.formatPassed || f.Name == "format" })
+
+	if err := applyCommandArgs(&config, positional); err != nil {
+		return config, err
+	}
+	// the input flags are applied last, since which of them can be combined depends on the output and the command
+	for _, apply := range []func(*Config, *flagValues) error{applyOutputFlags, applyReportFlags, applyGraphFlags, applySelectionFlags, applyInputFlags} {
+		if err := apply(&config, values); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+// applyCommandArgs sets the command, or the kind and names of the resources to render, given as positional arguments
+func applyCommandArgs(config *Config, args []string) error {
+	// tolerate kubectl muscle memory like "rback get sa my-sa -n my-namespace"
+	if len(args) > 0 && contains(kubectlVerbs, args[0]) {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if args[0] == commandOrphanSA || args[0] == commandValidate || args[0] == commandRoleUsage {
+		config.command = args[0]
+	} else if args[0] == commandWhoAmI {
+		if len(args) < 2 {
+			return errors.New("Usage: rback whoami USER [GROUP...] (the kubectl plugin fills these in for the current user)")
+		}
+		config.resourceKind = kindIdentity
+		config.identity.user = args[1]
+		for _, arg := range args[2:] {
+			config.identity.groups = append(config.identity.groups, strings.Split(arg, ",")...)
+		}
+	} else if args[0] == "who-can" {
+		if len(args) < 3 {
+			return errors.New("Usage: rback who-can VERB RESOURCE [NAME]")
+		}
+		config.resourceKind = kindRule
+		config.whoCan.verb = args[1]
+		config.whoCan.resourceKind, config.whoCan.apiGroup = splitGroupResource(args[2])
+		if len(args) > 3 {
+			config.whoCan.resourceName = args[3]
+		}
+	} else {
+		config.resourceKind = normalizeKind(args[0])
+		// like kubectl, accept both "sa a b" and "sa a,b"
+		for _, arg := range args[1:] {
+			config.resourceNames = append(config.resourceNames, strings.Split(arg, ",")...)
+		}
+	}
+	return nil
+}
+
+// registerInputFlags registers the flags that select where the RBAC resources are read or fetched from
+func registerInputFlags(fs *flag.FlagSet, config *Config, values *flagValues) {
+	fs.StringVar(&values.inputFiles, "f", "", "The name of the file to use as input (otherwise stdin is used); multiple comma-delimited files are merged, as are the .json files of a directory")
+	fs.StringVar(&config.bundle, "bundle", "", "Write the inputs, the rendered graph and a manifest into this .tar.gz archive (replacing it if it exists), for exploring them offline with -from-bundle")
+	fs.StringVar(&config.fromBundle, "from-bundle", "", "Read the inputs from this .tar.gz archive written by -bundle, instead of from -f or stdin")
+	fs.StringVar(&config.bundleContext, "bundle-context", "", "The name of the context (or cluster) the inputs were fetched from, recorded in the manifest of -bundle")
+	fs.StringVar(&values.contexts, "contexts", "", "Comma-delimited list of kubeconfig contexts to fetch the RBAC resources from with kubectl (instead of reading them from -f or stdin), each rendered as a cluster of its own in one graph")
+	fs.StringVar(&config.kubectl, "kubectl", "kubectl", "The kubectl binary that -contexts and -watch run, e.g. oc")
+	fs.StringVar(&values.context, "context", "", "The kubeconfig context that -watch fetches the RBAC resources from (instead of the current one)")
+	fs.StringVar(&values.kubeconfig, "kubeconfig", "", "The kubeconfig file of the kubectl commands run for -contexts and -watch")
+	fs.StringVar(&values.server, "server", "", "The URL of the API server that the kubectl commands run for -contexts and -watch connect to, instead of the one in the kubeconfig")
+	fs.BoolVar(&values.insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "Whether the kubectl commands run for -contexts and -watch skip verifying the API server's certificate, e.g. for self-signed certificates in dev clusters")
+	fs.DurationVar(&config.watch, "watch", 0, "Fetch the RBAC resources from the cluster (of kubectl's current context) at this interval (e.g. 10s), and re-render them to the file given via -o whenever they change")
+}
+
+func applyInputFlags(config *Config, values *flagValues) error {
+	if values.inputFiles != "" {
+		var err error
+		if config.inputFiles, err = expandInputDirs(strings.Split(values.inputFiles, ",")); err != nil {
+			return err
+		}
+	}
+
+	if config.bundle != "" && config.watch > 0 {
+		return errors.New("-bundle can't be combined with -watch")
+	}
+	if config.fromBundle != "" && (len(config.inputFiles) > 0 || config.bundle != "" || config.watch > 0) {
+		return errors.New("-from-bundle reads the inputs from the bundle, so it can't be combined with -f, -bundle or -watch")
+	}
+
+	if config.watch > 0 && (len(config.inputFiles) > 0 || config.outputPath == "") {
+		return errors.New("-watch fetches the input from the cluster itself, so it requires an output file (-o) and can't be combined with -f")
+	}
+
+	if values.contexts != "" {
+		config.contexts = strings.Split(values.contexts, ",")
+		if len(config.inputFiles) > 0 || config.bundle != "" || config.fromBundle != "" || config.watch > 0 || config.splitBy != "" || config.reportOnly ||
+			config.command != "" || config.format != formatDot {
+			return errors.New("-contexts fetches the input itself and only renders a DOT graph (or an image), so it can't be combined with -f, -bundle, -from-bundle, -watch, -split-by, -report-only, -format other than dot, or commands")
+		}
+	}
+
+	if values.context != "" {
+		config.kubectlFlags = append(config.kubectlFlags, "--context", values.context)
+	}
+	if values.kubeconfig != "" {
+		config.kubectlFlags = append(config.kubectlFlags, "--kubeconfig", values.kubeconfig)
+	}
+	if values.server != "" {
+		config.kubectlFlags = append(config.kubectlFlags, "--server", values.server)
+	}
+	if values.insecureSkipTLSVerify {
+		config.kubectlFlags = append(config.kubectlFlags, "--insecure-skip-tls-verify")
+	}
+	if len(config.kubectlFlags) > 0 && len(config.contexts) == 0 && config.watch == 0 {
+		return errors.New("-context, -kubeconfig, -server and -insecure-skip-tls-verify only apply to the kubectl commands run for -contexts and -watch, not to files given via -f, bundles or stdin")
+	}
+	if values.context != "" && len(config.contexts) > 0 {
+		return errors.New("-context can't be combined with -contexts, which sets the context of each kubectl command")
+	}
+	return nil
+}
+
+// registerOutputFlags registers the flags that select the output format and where it's written to
+func registerOutputFlags(fs *flag.FlagSet, config *Config, values *flagValues) {
+	fs.StringVar(&config.format, "format", formatDot, "The output format: 'dot' renders the graph, 'graphml' renders it as GraphML (e.g. for yEd), 'html' renders it as an interactive page, 'json' writes its nodes, edges and access rules as JSON, 'markdown' writes a report with the subjects, findings and graph, 'metrics' prints statistics in the Prometheus text format, 'yaml' prints the parsed resources (which can be read back via -f)")
+	fs.StringVar(&config.outputPath, "o", "", "The file to write to instead of stdout (or the directory, when using -split-by); unless -format is given, the format is inferred from its extension, and .png, .svg, .pdf or .jpg files are rendered using Graphviz' dot")
+	fs.BoolVar(&config.gzip, "gzip", false, "Whether to compress the output with gzip (implied by -o with a .gz extension, e.g. rbac.dot.gz)")
+	fs.StringVar(&config.splitBy, "split-by", "", "Write one file per namespace ('namespace') into the directory given by -o, plus an index.html, instead of writing everything to stdout")
+	fs.StringVar(&values.markdownSections, "markdown-sections", strings.Join(markdownSections, ","), "Comma-delimited list of the sections to include with -format markdown: "+strings.Join(markdownSections, ", "))
+	fs.IntVar(&config.maxNodes, "max-nodes", 0, "Abort instead of rendering a graph with more nodes than this (not counting the legend), e.g. to protect Graphviz from running out of memory on large clusters")
+	fs.BoolVar(&config.validate, "validate", false, "Check that the generated DOT is valid (for testing rback itself)")
+}
+
+func applyOutputFlags(config *Config, values *flagValues) error {
+	// unless -format is given, it's inferred from the extension of -o, rendering an image for e.g. -o rbac.png, and
+	// compressing the output for e.g. -o rbac.dot.gz
+	if config.outputPath != "" && config.splitBy == "" && !config.reportOnly {
+		outputPath := config.outputPath
+		if strings.HasSuffix(strings.ToLower(outputPath), ".gz") {
+			config.gzip = true
+			outputPath = outputPath[:len(outputPath)-len(".gz")]
+		}
+		if format, imageFormat, ok := inferFormat(outputPath); ok && imageFormat != "" && (!values.formatPassed || config.format == formatDot) {
+			config.format, config.imageFormat = format, imageFormat
+		} else if ok && !values.formatPassed {
+			config.format = format
+		}
+	}
+
+	if config.gzip && (config.splitBy != "" || config.reportOnly) {
+		return errors.New("-gzip can't be combined with -split-by or -report-only")
+	}
+
+	if config.reportOnly {
+		if config.format == formatDot {
+			config.format = formatText
+		}
+		if !contains(reportFormats, config.format) {
+			return fmt.Errorf("-report-only only supports -format %s", strings.Join(reportFormats, " or "))
+		}
+		if config.splitBy != "" || config.watch > 0 || config.outputPath != "" {
+			return errors.New("-report-only writes the report to stdout, so it can't be combined with -split-by, -watch or -o")
+		}
+	} else if !contains(formats, config.format) {
+		return fmt.Errorf("Unknown output format %q, expected one of: %s", config.format, strings.Join(formats, ", "))
+	}
+
+	if config.splitBy != "" {
+		if !contains(splitByValues, config.splitBy) {
+			return fmt.Errorf("Unknown -split-by %q, expected one of: %s", config.splitBy, strings.Join(splitByValues, ", "))
+		}
+		if config.outputPath == "" {
+			return errors.New("-split-by requires an output directory (-o)")
+		}
+	}
+
+	config.markdownSections = strings.Split(values.markdownSections, ",")
+	for _, section := range config.markdownSections {
+		if !contains(markdownSections, section) {
+			return fmt.Errorf("Unknown -markdown-sections %q, expected any of: %s", section, strings.Join(markdownSections, ", "))
+		}
+	}
+
+	if config.maxNodes < 0 {
+		return errors.New("-max-nodes must not be negative")
+	}
+	return nil
+}
+
+// registerSelectionFlags registers the flags that select which resources, and which of their access rules, are rendered
+func registerSelectionFlags(fs *flag.FlagSet, config *Config, values *flagValues) {
+	fs.StringVar(&values.namespaces, "n", "", "The namespace to render (also supports multiple, comma-delimited namespaces)")
+	fs.StringVar(&values.namespaces, "namespace", "", "Same as -n, for compatibility with kubectl")
+	fs.StringVar(&values.excludedNamespaces, "exclude-namespaces", "", "Comma-delimited list of namespaces whose ServiceAccounts, Roles and RoleBindings are ignored (also when selected via -n)")
+	fs.StringVar(&values.ignoredPrefixes, "ignore-prefixes", "system:", "Comma-delimited list of (Cluster)Role(Binding) prefixes to ignore ('none' to not ignore anything)")
+	fs.BoolVar(&config.collapseIgnored, "collapse-ignored", false, "Whether to render a single summary node per subject for its bindings matching -ignore-prefixes, instead of leaving them out")
+	fs.StringVar(&values.subjectKinds, "subject-kind", "", "Comma-delimited list of subject kinds to render (serviceaccount, user, group); all kinds are rendered by default")
+	fs.DurationVar(&config.since, "since", 0, "Only render (Cluster)Roles and (Cluster)RoleBindings created within this duration (e.g. 24h), and the subjects bound by them")
+	fs.Var(&config.onlyAnnotated, "only-annotated", "Only render the objects with this annotation as key=value (e.g. rback.io/changed=true), and the subjects and roles bound by annotated bindings")
+	fs.StringVar(&config.resourceName, "resource-name", "", "Only render access rules that apply to resources of this name (i.e. list it in their resourceNames, or aren't restricted to any), and the roles (and their bindings) that have such rules")
+	fs.BoolVar(&config.writesOnly, "writes-only", false, "Whether to leave out access rules that only grant read-only verbs (see -read-only-verbs), and the roles (and their bindings) that only have such rules")
+	fs.StringVar(&values.readOnlyVerbs, "read-only-verbs", "get,list,watch", "Comma-delimited list of the verbs that -writes-only considers read-only")
+	fs.StringVar(&values.hiddenResources, "hide-resources", "", "Comma-delimited list of resources to leave out of the rendered access rules, optionally qualified with their API group (e.g. events,leases.coordination.k8s.io)")
+	fs.StringVar(&values.hiddenVerbs, "hide-verbs", "", "Comma-delimited list of verbs to leave out of the rendered access rules (e.g. list,watch), to focus on the others")
+	fs.StringVar(&values.noRulesFor, "no-rules-for", "", "Comma-delimited list of (Cluster)Role names whose access rules shouldn't be rendered (e.g. cluster-admin,admin,edit,view)")
+	fs.StringVar(&values.focus, "focus", "", "Emphasize the roles granting VERB:RESOURCE (e.g. *:secrets) and everything bound to them, dimming everything else")
+	fs.StringVar(&config.pathTo, "path-to", "", "Only render the chains of grants (including impersonation and writing RBAC resources) through which subjects can gain access equivalent to this role ('cluster-admin'), and report them")
+	fs.BoolVar(&config.hideDefaultSA, "hide-default-sa", false, "Whether to hide the 'default' ServiceAccounts (or mute them, if they have non-default bindings), unless explicitly selected")
+	fs.BoolVar(&config.showEmptyBindings, "include-rolebindings-without-subjects", true, "Whether to render (Cluster)RoleBindings that have no (non-ignored) subjects")
+	fs.BoolVar(&config.whoCan.showMatchedOnly, "show-matched-rules-only", false, "When running who-can, only show the matched rule instead of all rules specified in the role")
+}
+
+func applySelectionFlags(config *Config, values *flagValues) error {
+	config.namespaces = strings.Split(values.namespaces, ",")
+	if values.excludedNamespaces != "" {
+		config.excludedNamespaces = strings.Split(values.excludedNamespaces, ",")
+	}
+	if values.ignoredPrefixes != "none" {
+		config.ignoredPrefixes = strings.Split(values.ignoredPrefixes, ",")
+	}
+
+	if values.subjectKinds != "" {
+		for _, kind := range strings.Split(values.subjectKinds, ",") {
+			kind = normalizeKind(kind)
+			if kind != kindServiceAccount && kind != kindUser && kind != kindGroup {
+				return fmt.Errorf("Unknown -subject-kind %q, expected serviceaccount, user or group", kind)
+			}
+			config.subjectKinds = append(config.subjectKinds, kind)
+		}
+	}
+
+	config.readOnlyVerbs = strings.Split(values.readOnlyVerbs, ",")
+	if values.hiddenResources != "" {
+		config.hiddenResources = strings.Split(values.hiddenResources, ",")
+	}
+	if values.hiddenVerbs != "" {
+		config.hiddenVerbs = strings.Split(values.hiddenVerbs, ",")
+	}
+	if values.noRulesFor != "" {
+		config.noRulesFor = strings.Split(values.noRulesFor, ",")
+	}
+
+	if values.focus != "" {
+		var err error
+		if config.focus, err = parseFocus(values.focus); err != nil {
+			return fmt.Errorf("Invalid -focus: %v", err)
+		}
+	}
+
+	if config.pathTo != "" {
+		if !contains(pathTargets, config.pathTo) {
+			return fmt.Errorf("Unknown -path-to %q, expected one of: %s", config.pathTo, strings.Join(pathTargets, ", "))
+		}
+		config.showImpersonation = true // impersonation is part of the paths
+	}
+	return nil
+}
+
+// registerGraphFlags registers the flags that control how the graph is rendered
+func registerGraphFlags(fs *flag.FlagSet, config *Config, values *flagValues) {
+	fs.BoolVar(&config.showLegend, "show-legend", true, "Whether to show the legend or not")
+	fs.BoolVar(&config.legendOnly, "legend-only", false, "Only render the legend (without reading any input), e.g. to render it once for many graphs rendered with -show-legend=false")
+	fs.BoolVar(&config.legendPresentOnly, "legend-present-only", false, "Whether to only show the legend entries for the kinds of bindings (and missing subjects) that are present in the graph")
+	fs.BoolVar(&config.overview, "overview", false, "Whether to render a row of nodes at the top linking to each namespace, for orienting in large graphs (clickable in SVG output)")
+	fs.BoolVar(&config.showRules, "show-rules", true, "Whether to render RBAC access rules (e.g. \"get pods\") or not")
+	fs.BoolVar(&config.annotateRulesScope, "annotate-rules-scope", true, "Whether to annotate the access rules of ClusterRoles bound by RoleBindings with the namespace they're scoped to")
+	fs.StringVar(&config.rulesStyle, "rules-style", rulesStyleNote, "How to render access rules: 'note' lists them as text, 'table' in columns for verbs, resources, apiGroups and resourceNames")
+	fs.StringVar(&config.rulesFormat, "rules-format", rulesFormatFull, "Which access rules to render: 'full' renders them as defined, 'compact' merges those that only differ in their verbs, or only in their resources, into a single rule (granting the same access)")
+	fs.BoolVar(&config.highlightScopedRules, "highlight-scoped-rules", false, "Whether to highlight access rules that are restricted to specific resourceNames (i.e. narrow grants)")
+	fs.BoolVar(&config.bindingsOnly, "bindings-only", false, "Whether to only render subjects and their bindings (without roles and access rules) for a high-level overview")
+	fs.BoolVar(&config.compact, "compact", false, "Whether to render each binding and its role as a single node, for overview diagrams with fewer nodes")
+	fs.StringVar(&config.edgeLabel, "edge-label", "", "Label the edges from subjects to bindings with the binding's name ('binding'), the name of the role it references ('role') or the kind of that role ('kind')")
+	fs.BoolVar(&config.effectiveRules, "effective-rules", false, "Whether to render the union of all access rules granted to each ServiceAccount across all its bindings")
+	fs.BoolVar(&config.mergeClusterRoles, "merge-clusterroles", false, "Whether to render a single node per ClusterRole instead of one per namespace it's bound in")
+	fs.BoolVar(&config.mergeRules, "merge-rules-across-bindings", false, "Whether to render the rules of each ClusterRole once, shared by all its nodes, instead of once per namespace it's bound in")
+	fs.BoolVar(&config.showDefaultRoleHierarchy, "show-default-role-hierarchy", false, "Whether to connect the default ClusterRoles view, edit and admin according to how Kubernetes aggregates them into each other")
+	fs.BoolVar(&config.colorNamespaces, "color-namespaces", false, "Whether to give each namespace (and its ServiceAccounts) a distinct color")
+	fs.StringVar(&config.groupByLabel, "group-by-label", "", "Group namespaces into clusters by the value of this label of the Namespaces in the input (e.g. team); namespaces without it are grouped as 'ungrouped'")
+	fs.BoolVar(&config.showAutomount, "show-automount", false, "Whether to mark ServiceAccounts whose token isn't automounted into pods (automountServiceAccountToken: false)")
+	fs.BoolVar(&config.showPermissionCount, "show-permission-count", false, "Whether to show the number of distinct (verb, apiGroup, resource) triples each ServiceAccount is granted across all its bindings, as a hint where to focus reviews")
+	fs.BoolVar(&config.showSASecrets, "show-sa-secrets", false, "Whether to draw the Secrets that ServiceAccounts reference as token secrets or imagePullSecrets")
+	fs.BoolVar(&config.markOrphans, "mark-orphans", false, "Whether to dim ServiceAccounts that aren't bound to any role (see also the orphan-sa command)")
+	fs.BoolVar(&config.showImpersonation, "show-impersonation", false, "Whether to draw edges from subjects that can impersonate other users, groups or ServiceAccounts to these identities")
+	fs.StringVar(&values.nodeLabelTemplate, "node-label-template", "", "A Go template for the labels of subject, binding and role nodes, with the fields .Kind, .Namespace, .Name and .BindingCount (e.g. '{{.Namespace}}/{{.Name}}')")
+	fs.StringVar(&config.title, "title", "", "A title to render at the top of the graph")
+	fs.StringVar(&config.caption, "caption", "", "A caption to render at the bottom of the graph")
+	fs.Float64Var(&config.rankSep, "ranksep", 0, "The minimum distance between ranks (in inches) for spreading out crowded graphs; Graphviz' default is used if not set")
+	fs.Float64Var(&config.nodeSep, "nodesep", 0, "The minimum distance between nodes of the same rank (in inches); Graphviz' default is used if not set")
+	fs.StringVar(&config.splines, "splines", "", "How to route edges: "+strings.Join(splineStyles, ", ")+"; Graphviz' default is used if not set")
+	fs.StringVar(&config.orientation, "orientation", orientationSubjectFirst, "'subject-first' renders subjects at the top and their roles below, 'role-first' renders roles at the top and the subjects using them below (to see who uses a role)")
+	fs.Var(&config.graphAttrs, "graph-attr", "A Graphviz graph attribute as key=value (e.g. newrank=false), overriding the one set by rback, if any; can be given several times")
+	fs.StringVar(&config.urlTemplate, "url-template", "", "Make nodes link to this URL (e.g. in SVG output), with {kind}, {namespace} and {name} replaced by those of the node")
+}
+
+func applyGraphFlags(config *Config, values *flagValues) error {
+	if config.legendOnly {
+		if config.format != formatDot {
+			return fmt.Errorf("-legend-only is only supported with -format %s", formatDot)
+		}
+		config.showLegend = true
+	}
+
+	if !contains(rulesStyles, config.rulesStyle) {
+		return fmt.Errorf("Unknown -rules-style %q, expected one of: %s", config.rulesStyle, strings.Join(rulesStyles, ", "))
+	}
+	if !contains(rulesFormats, config.rulesFormat) {
+		return fmt.Errorf("Unknown -rules-format %q, expected one of: %s", config.rulesFormat, strings.Join(rulesFormats, ", "))
+	}
+	if config.edgeLabel != "" && !contains(edgeLabels, config.edgeLabel) {
+		return fmt.Errorf("Unknown -edge-label %q, expected one of: %s", config.edgeLabel, strings.Join(edgeLabels, ", "))
+	}
+
+	if values.nodeLabelTemplate != "" {
+		var err error
+		if config.nodeLabelTemplate, err = parseNodeLabelTemplate(values.nodeLabelTemplate); err != nil {
+			return fmt.Errorf("Invalid -node-label-template: %v", err)
+		}
+	}
+
+	if config.rankSep < 0 || config.nodeSep < 0 {
+		return errors.New("-ranksep and -nodesep must not be negative")
+	}
+	if config.splines != "" && !contains(splineStyles, config.splines) {
+		return fmt.Errorf("Unknown -splines %q, expected one of: %s", config.splines, strings.Join(splineStyles, ", "))
+	}
+	if !contains(orientations, config.orientation) {
+		return fmt.Errorf("Unknown -orientation %q, expected one of: %s", config.orientation, strings.Join(orientations, ", "))
+	}
+	return nil
+}
+
+// registerReportFlags registers the flags of the analysis passes, their report, and the exit status they result in
+func registerReportFlags(fs *flag.FlagSet, config *Config, values *flagValues) {
+	fs.BoolVar(&config.reportWildcards, "report-wildcards", false, "Whether to report (to stderr) all subjects that are granted all verbs or all resources (*)")
+	fs.BoolVar(&config.reportSecretReaders, "report-secret-readers", false, "Whether to report (to stderr) all subjects that can read all secrets (i.e. not restricted via resourceNames)")
+	fs.BoolVar(&config.reportSecretWriters, "report-secret-writers", false, "Whether to report (to stderr) all subjects that can create, update, patch or delete secrets")
+	fs.BoolVar(&config.reportImpersonation, "report-impersonation", false, "Whether to report (to stderr) all subjects that can impersonate users, groups or ServiceAccounts")
+	fs.BoolVar(&config.reportPublicAccess, "report-public-access", false, "Whether to report (to stderr) all permissions granted to all authenticated or unauthenticated users")
+	fs.BoolVar(&config.reportEscalation, "report-escalation", false, "Whether to report (to stderr) all subjects that can create, update, patch, bind or escalate (Cluster)Roles or (Cluster)RoleBindings, i.e. grant themselves further permissions")
+	fs.BoolVar(&config.reportCrossNamespace, "report-cross-namespace", false, "Whether to report (to stderr) all access rules granted to ServiceAccounts outside of their own namespace (i.e. cluster-wide or in other namespaces)")
+	fs.BoolVar(&config.reportOrphans, "report-orphans", false, "Whether to report (to stderr) bindings referencing missing roles (well-known ClusterRoles separately) and ServiceAccounts that aren't bound to any role")
+	fs.BoolVar(&config.reportOnly, "report-only", false, "Only write the report of the analysis passes (all, unless some are enabled via -report-*) to stdout, as -format text or json, instead of rendering the graph")
+	fs.BoolVar(&config.stats, "stats", false, "Whether to write (to stderr) the number of ServiceAccounts, Roles and RoleBindings of each namespace, of its ServiceAccounts with write access, and of its RoleBindings referencing ClusterRoles; as JSON with -format json")
+	fs.StringVar(&config.failOn, "fail-on", "", "Exit with a non-zero status if the report contains findings of this severity or higher ("+strings.Join(severities, ", ")+")")
+	fs.StringVar(&values.suppressedCodes, "suppress", "", "Comma-delimited list of finding codes (e.g. RBACK007) to leave out of the report, and thus -fail-on")
+	fs.BoolVar(&values.failOnRisky, "fail-on-risky", false, "Exit with a non-zero status if any subject is granted wildcards, writing secrets, escalating privileges or impersonation, logging these grants to stderr (see -risk-policy)")
+	fs.StringVar(&values.riskPolicy, "risk-policy", "", "A JSON file mapping finding codes to fatal, warn or ignore (e.g. {\"RBACK010\": \"warn\"}), overriding the defaults of -fail-on-risky, which it implies")
+	fs.BoolVar(&config.failOnEmpty, "fail-on-empty", false, "Whether to exit with a non-zero status if the rendered graph doesn't contain any subjects")
+}
+
+func applyReportFlags(config *Config, values *flagValues) error {
+	if config.failOn != "" && !contains(severities, config.failOn) {
+		return fmt.Errorf("Unknown -fail-on severity %q, expected one of: %s", config.failOn, strings.Join(severities, ", "))
+	}
+
+	if values.failOnRisky || values.riskPolicy != "" {
+		var err error
+		if config.riskPolicy, err = loadRiskPolicy(values.riskPolicy); err != nil {
+			return fmt.Errorf("Invalid -risk-policy %s: %v", values.riskPolicy, err)
+		}
+	}
+
+	if values.suppressedCodes != "" {
+		config.suppressedCodes = strings.Split(strings.ToUpper(values.suppressedCodes), ",")
+		for _, code := range config.suppressedCodes {
+			if !contains(findingCodes, code) {
+				return fmt.Errorf("Unknown -suppress finding code %q, expected one of: %s", code, strings.Join(findingCodes, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// registerLoggingFlags registers the flags that control what's logged to stderr
+func registerLoggingFlags(fs *flag.FlagSet, config *Config) {
+	fs.BoolVar(&config.verbose, "v", false, "Log additional diagnostics (e.g. timing of each phase) to stderr")
+	fs.BoolVar(&config.quiet, "quiet", false, "Only log fatal errors to stderr")
+	fs.BoolVar(&config.progress, "progress", false, "Report (to stderr) the number of resources read and rendered as each phase completes")
+}
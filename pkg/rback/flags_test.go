@@ -0,0 +1,31 @@
+package rback
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestInvalidFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-format", "svg"}, "Unknown output format"},
+		{[]string{"-report-only", "-o", "report.txt"}, "-report-only writes the report to stdout"},
+		{[]string{"-subject-kind", "pod"}, "Unknown -subject-kind"},
+		{[]string{"-rules-style", "list"}, "Unknown -rules-style"},
+		{[]string{"-suppress", "RBACK999"}, "Unknown -suppress finding code"},
+		{[]string{"-watch", "10s", "-f", "../../examples/role-usage.json", "-o", "rbac.dot"}, "-watch fetches the input from the cluster itself"},
+		{[]string{"-contexts", "dev", "-format", "json"}, "-contexts fetches the input itself"},
+		{[]string{"who-can", "get"}, "Usage: rback who-can"},
+	}
+	for _, test := range tests {
+		fs := flag.NewFlagSet("rback", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		if _, err := parseConfig(fs, test.args, false); err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("%v: expected an error starting with %q, got %v", test.args, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package rback
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emicklei/dot"
+)
+
+// Focus selects the roles granting a verb on a resource. These roles, their bindings and subjects are emphasized, while
+// everything else is dimmed (but still rendered, to preserve the context).
+type Focus struct {
+	verb, resource, apiGroup string
+}
+
+func parseFocus(s string) (Focus, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Focus{}, fmt.Errorf("Expected VERB:RESOURCE (e.g. get:secrets, *:secrets or get:certificates.cert-manager.io), but found %q", s)
+	}
+	resource, apiGroup := splitGroupResource(parts[1])
+	return Focus{verb: parts[0], resource: resource, apiGroup: apiGroup}, nil
+}
+
+func (f *Focus) enabled() bool {
+	return f.verb != ""
+}
+
+func (f *Focus) matches(rule Rule) bool {
+	return (f.verb == "*" || contains(rule.verbs, "*") || contains(rule.verbs, f.verb)) &&
+		(f.resource == "*" || contains(rule.resources, "*") || contains(rule.resources, f.resource)) &&
+		rule.matchesAPIGroup(f.apiGroup)
+}
+
+// focusedResources holds everything that's emphasized when using -focus
+type focusedResources struct {
+	roles    map[NamespacedName]bool
+	bindings map[NamespacedName]bool
+	subjects map[KindNamespacedName]bool
+}
+
+// findFocusedResources is the first pass of rendering with -focus: it marks the matching roles and everything bound to them
+func (r *Rback) findFocusedResources() *focusedResources {
+	focused := &focusedResources{
+		roles:    make(map[NamespacedName]bool),
+		bindings: make(map[NamespacedName]bool),
+		subjects: make(map[KindNamespacedName]bool),
+	}
+	for _, roles := range r.permissions.Roles {
+		for _, role := range roles {
+			for _, rule := range role.rules {
+				if r.config.focus.matches(rule) {
+					focused.roles[role.NamespacedName] = true
+					break
+				}
+			}
+		}
+	}
+	for _, bindings := range r.permissions.RoleBindings {
+		for _, binding := range bindings {
+			if focused.roles[binding.role] {
+				focused.bindings[binding.NamespacedName] = true
+				for _, subject := range binding.subjects {
+					focused.subjects[subject] = true
+				}
+			}
+		}
+	}
+	return focused
+}
+
+// applyFocus dims the node unless it's in focus (or -focus isn't used)
+func (r *Rback) applyFocus(node dot.Node, inFocus bool) {
+	if r.focused != nil && !inFocus {
+		dimNode(node)
+	}
+}
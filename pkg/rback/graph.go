@@ -0,0 +1,439 @@
+package rback
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/emicklei/dot"
+)
+
+func newGraph() *dot.Graph {
+	g := dot.NewGraph(dot.Directed)
+	g.Attr("newrank", "true") // global rank instead of per-subgraph (ensures access rules are always in the same place (at bottom))
+	return g
+}
+
+// setGraphTitle sets the label of the graph itself, which (unlike the label of the LEGEND cluster) is placed above everything else
+func setGraphTitle(g *dot.Graph, title string) {
+	g.Attr("label", title)
+	g.Attr("labelloc", "t")
+}
+
+// newCaptionNode renders the caption as a borderless node in a (non-cluster) subgraph that's ranked below all other nodes
+func newCaptionNode(g *dot.Graph, caption string) dot.Node {
+	footer := g.Subgraph("caption")
+	footer.Attr("rank", "sink")
+	return footer.Node("caption").
+		Attr("label", caption).
+		Attr("shape", "plaintext")
+}
+
+func newNamespaceSubgraph(g *dot.Graph, ns string) *dot.Graph {
+	if ns == "" {
+		return g
+	}
+	gns := g.Subgraph(ns, dot.ClusterOption{})
+	gns.Attr("style", "dashed")
+	return gns
+}
+
+// newOverviewNode renders the entry of a namespace in the overview (see -overview)
+func newOverviewNode(g *dot.Graph, ns string) dot.Node {
+	return g.Node("overview-"+ns).
+		Attr("label", ns).
+		Attr("shape", "tab").
+		Attr("style", "filled").
+		Attr("fillcolor", "#f4f4f4").
+		Attr("color", "#c8c8c8")
+}
+
+// newOverviewAnchorNode renders an invisible node in the cluster of a namespace, for the edge from its overview node
+// to end at (edges can only connect nodes, but can be clipped at the border of a cluster)
+func newOverviewAnchorNode(gns *dot.Graph, ns string) dot.Node {
+	return gns.Node("overview-anchor-"+ns).
+		Attr("shape", "point").
+		Attr("style", "invis")
+}
+
+// newGroupSubgraph renders a group of namespaces (see -group-by-label) as a cluster around their own clusters
+func newGroupSubgraph(g *dot.Graph, label string) *dot.Graph {
+	group := g.Subgraph(label, dot.ClusterOption{})
+	group.Attr("style", "rounded")
+	group.Attr("penwidth", "2.0")
+	return group
+}
+
+// namespacePalette holds pairs of background and border colors for namespaces, when using -color-namespaces
+var namespacePalette = []struct{ background, border string }{
+	{"#fde2e4", "#e5737f"},
+	{"#e2f0cb", "#8bb35a"},
+	{"#dbe9f6", "#5b8fc7"},
+	{"#fff1c1", "#d4a514"},
+	{"#e8dff5", "#9575cd"},
+	{"#d7f2ee", "#3fa999"},
+	{"#fce1c8", "#e08a3c"},
+	{"#eeeeee", "#8c8c8c"},
+}
+
+// namespaceColor derives the color of a namespace from a hash of its name, so it's stable across runs
+func namespaceColor(ns string) struct{ background, border string } {
+	h := fnv.New32a()
+	h.Write([]byte(ns))
+	return namespacePalette[h.Sum32()%uint32(len(namespacePalette))]
+}
+
+func colorNamespaceSubgraph(gns *dot.Graph, ns string) {
+	color := namespaceColor(ns)
+	gns.Attr("style", "dashed,filled")
+	gns.Attr("fillcolor", color.background)
+	gns.Attr("color", color.border)
+}
+
+// subjectNodeID includes the namespace of ServiceAccounts, since ServiceAccounts in different namespaces can have the
+// same name (e.g. "default"), which would otherwise be merged into a single node
+func subjectNodeID(kind, namespace, name string) string {
+	if namespace == "" {
+		return kind + "-" + name
+	}
+	return kind + "-" + namespace + "/" + name
+}
+
+// roleBindingNodeID includes the namespace, since RoleBindings in different namespaces can have the same name (e.g. one
+// "view" binding per namespace), which would otherwise be merged into a single node
+func roleBindingNodeID(namespace, name string) string {
+	return "rb-" + namespace + "/" + name
+}
+
+func clusterRoleBindingNodeID(name string) string {
+	return "crb-" + name
+}
+
+func compactBindingNodeID(namespace, name string) string {
+	return "compact-" + namespace + "/" + name
+}
+
+func roleNodeID(namespace, name string) string {
+	return "r-" + namespace + "/" + name
+}
+
+func clusterRoleNodeID(bindingNamespace, roleName string) string {
+	return "cr-" + bindingNamespace + "/" + roleName
+}
+
+// rulesNodeID includes the kind of the role, since a Role and a ClusterRole bound in the same namespace can have the same name
+func rulesNodeID(kind, namespace, roleName string) string {
+	return "rules-" + iff(kind == kindClusterRole, "cr-", "r-") + namespace + "/" + roleName
+}
+
+func effectiveRulesNodeID(namespace, subjectName string) string {
+	return "effective-rules-" + namespace + "/" + subjectName
+}
+
+func newSubjectNode0(g *dot.Graph, kind, namespace, name string, exists, highlight bool) dot.Node {
+	return g.Node(subjectNodeID(kind, namespace, name)).
+		Box().
+		Attr("label", formatLabel(fmt.Sprintf("%s\n(%s)", name, kind), highlight)).
+		Attr("style", iff(exists, "filled", "dotted")).
+		Attr("color", iff(exists, "black", "red")).
+		Attr("penwidth", iff(highlight || !exists, "2.0", "1.0")).
+		Attr("fillcolor", "#2f6de1").
+		Attr("fontcolor", iff(exists, "#f0f0f0", "#030303"))
+}
+
+// markTokenNotAutomounted mutes a ServiceAccount node whose token isn't automounted into pods, since its permissions
+// are only usable by pods that explicitly mount the token
+func markTokenNotAutomounted(node dot.Node, label string, highlight bool) {
+	node.Attr("label", formatLabel(label+"\n[token not automounted]", highlight)).
+		Attr("fillcolor", "#9ab5ea").
+		Attr("fontcolor", "#030303")
+}
+
+// markServiceAccountsGroup draws a virtual group of ServiceAccounts with a double border, as it stands for many subjects
+func markServiceAccountsGroup(node dot.Node, label string, highlight bool) {
+	node.Attr("label", formatLabel(label, highlight)).
+		Attr("peripheries", "2")
+}
+
+// markPublicAccess draws a group of all (un)authenticated users in bold red, as anything bound to it is granted to
+// everyone who can reach the API server
+func markPublicAccess(node dot.Node) {
+	node.Attr("style", "filled,bold").
+		Attr("color", "red").
+		Attr("penwidth", "3.0").
+		Attr("fillcolor", "#d62728").
+		Attr("fontcolor", "#f0f0f0")
+}
+
+func secretNodeID(ns, name string) string {
+	return "secret-" + ns + "/" + name
+}
+
+func newSecretNode0(g *dot.Graph, id, name string) dot.Node {
+	return g.Node(id).
+		Attr("label", name+"\n(Secret)").
+		Attr("shape", "cylinder").
+		Attr("style", "filled").
+		Attr("fillcolor", "#e0e0e0").
+		Attr("fontcolor", "#030303")
+}
+
+// newServiceAccountToSecretEdge is dashed, since referencing a Secret doesn't grant any permissions
+func newServiceAccountToSecretEdge(saNode, secretNode dot.Node, imagePull bool) dot.Edge {
+	e := edge(saNode, secretNode).Attr("style", "dashed")
+	if imagePull {
+		e.Attr("label", "imagePullSecret")
+	}
+	return e
+}
+
+func ignoredBindingsNodeID(subjectID string) string {
+	return "ignored-" + subjectID
+}
+
+// newIgnoredBindingsNode summarizes the bindings of a subject that match -ignore-prefixes, for -collapse-ignored
+func newIgnoredBindingsNode(g *dot.Graph, id, label string) dot.Node {
+	return g.Node(id).
+		Attr("label", label).
+		Attr("shape", "octagon").
+		Attr("style", "dashed").
+		Attr("color", "#808080").
+		Attr("fontcolor", "#808080")
+}
+
+func anyIdentityNodeID(kind, scope string) string {
+	return "any-" + kind + "-" + scope
+}
+
+// newAnyIdentityNode represents all identities of a kind that can be impersonated through an unrestricted rule
+func newAnyIdentityNode(g *dot.Graph, kind, scope string) dot.Node {
+	return g.Node(anyIdentityNodeID(kind, scope)).
+		Box().
+		Attr("label", anyIdentityLabel(kind, scope)).
+		Attr("style", "filled,bold").
+		Attr("color", "red").
+		Attr("fillcolor", "#f5b7b1").
+		Attr("fontcolor", "#030303")
+}
+
+func newRoleBindingNode(g *dot.Graph, namespace, name string, highlight bool) dot.Node {
+	return g.Node(roleBindingNodeID(namespace, name)).
+		Attr("label", formatLabel(name, highlight)).
+		Attr("shape", "octagon").
+		Attr("style", "filled").
+		Attr("penwidth", iff(highlight, "2.0", "1.0")).
+		Attr("fillcolor", "#ffcc00").
+		Attr("fontcolor", "#030303")
+}
+
+func newClusterRoleBindingNode(g *dot.Graph, name string, highlight bool) dot.Node {
+	return g.Node(clusterRoleBindingNodeID(name)).
+		Attr("label", formatLabel(name, highlight)).
+		Attr("shape", "doubleoctagon").
+		Attr("style", "filled").
+		Attr("penwidth", iff(highlight, "2.0", "1.0")).
+		Attr("fillcolor", "#ffcc00").
+		Attr("fontcolor", "#030303")
+}
+
+func newRoleNode(g *dot.Graph, namespace, name string, exists, highlight bool) dot.Node {
+	node := g.Node(roleNodeID(namespace, name)).
+		Attr("label", formatLabel(name, highlight)).
+		Attr("shape", "octagon").
+		Attr("style", iff(exists, "filled", "dotted")).
+		Attr("color", iff(exists, "black", "red")).
+		Attr("penwidth", iff(highlight || !exists, "2.0", "1.0")).
+		Attr("fillcolor", "#ff9900").
+		Attr("fontcolor", "#030303")
+	g.Root().AddToSameRank("Roles", node)
+	return node
+}
+
+func newClusterRoleNode(g *dot.Graph, bindingNamespace, roleName string, exists, highlight bool) dot.Node {
+	node := g.Node(clusterRoleNodeID(bindingNamespace, roleName)).
+		Attr("label", formatLabel(roleName, highlight)).
+		Attr("shape", "doubleoctagon").
+		Attr("style", iff(exists, iff(bindingNamespace == "", "filled", "filled,dashed"), "dotted")).
+		Attr("color", iff(exists, "black", "red")).
+		Attr("penwidth", iff(highlight || !exists, "2.0", "1.0")).
+		Attr("fillcolor", "#ff9900").
+		Attr("fontcolor", "#030303")
+	g.Root().AddToSameRank("Roles", node)
+	return node
+}
+
+// newCompactBindingNode0 renders a binding and its role stacked in a single node, shaped like the binding and filled
+// with the colors of both
+func newCompactBindingNode0(g *dot.Graph, id, bindingName, roleName string, clusterRoleBinding, roleExists, highlight bool) dot.Node {
+	return g.Node(id).
+		Attr("label", formatLabel(bindingName+"\n→ "+roleName, highlight)).
+		Attr("shape", iff(clusterRoleBinding, "doubleoctagon", "octagon")).
+		Attr("style", iff(roleExists, "filled", "filled,dotted")).
+		Attr("color", iff(roleExists, "black", "red")).
+		Attr("penwidth", iff(highlight || !roleExists, "2.0", "1.0")).
+		Attr("fillcolor", "#ffcc00:#ff9900").
+		Attr("gradientangle", "270").
+		Attr("fontcolor", "#030303")
+}
+
+// newRulesNode0 draws the rules of cluster-wide grants (a ClusterRole bound by a ClusterRoleBinding) with a red border,
+// so they stand out from the rules that only apply in a namespace
+func newRulesNode0(g *dot.Graph, kind, namespace, roleName, rulesHTML string, clusterWide, highlight bool) dot.Node {
+	return g.Node(rulesNodeID(kind, namespace, roleName)).
+		Attr("label", dot.HTML(rulesHTML)).
+		Attr("shape", "note").
+		Attr("color", iff(clusterWide, "#c0392b", "black")).
+		Attr("penwidth", iff(highlight, "2.0", "1.0"))
+}
+
+func newEffectiveRulesNode0(g *dot.Graph, namespace, subjectName, rulesHTML string) dot.Node {
+	return g.Node(effectiveRulesNodeID(namespace, subjectName)).
+		Attr("label", dot.HTML(rulesHTML)).
+		Attr("shape", "note").
+		Attr("style", "filled").
+		Attr("fillcolor", "#dbe6fa")
+}
+
+// applyNodeColors overrides the fill and font colors of a node, e.g. to highlight sensitive roles
+func applyNodeColors(node dot.Node, colors nodeColors) {
+	if colors.fill != "" {
+		node.Attr("fillcolor", colors.fill)
+	}
+	if colors.font != "" {
+		node.Attr("fontcolor", colors.font)
+	}
+}
+
+// dimNode greys out a node that isn't in focus
+func dimNode(node dot.Node) {
+	node.Attr("style", "filled").
+		Attr("color", "#c8c8c8").
+		Attr("fillcolor", "#eeeeee").
+		Attr("fontcolor", "#b0b0b0")
+}
+
+func regularLine(str string) string {
+	return escapeHTML(str) + `<br align="left"/>`
+}
+
+func boldLine(str string) string {
+	return "<b>" + escapeHTML(str) + "</b>" + `<br align="left"/>`
+}
+
+func italicLine(str string) string {
+	return "<i>" + escapeHTML(str) + "</i>" + `<br align="left"/>`
+}
+
+// ruleLine renders a rule as a line of a rules node
+func ruleLine(rule Rule, bold, scoped bool) string {
+	line := iff(bold, boldLine(rule.toHumanReadableString()), regularLine(rule.toHumanReadableString()))
+	return iff(scoped, scopedLine(line), line)
+}
+
+// rulesTable wraps the rows rendered by ruleRow (and rulesTableSpanningRow) into a table with a header, for
+// -rules-style table
+func rulesTable(rows string) string {
+	return `<table border="0" cellborder="1" cellspacing="0" cellpadding="3">` +
+		`<tr><td><b>verbs</b></td><td><b>resources</b></td><td><b>apiGroups</b></td><td><b>resourceNames</b></td></tr>` +
+		rows +
+		`</table>`
+}
+
+// ruleRow renders a rule as a row of a rules table, listing non-resource URLs as resources
+func ruleRow(rule Rule, bold, scoped bool) string {
+	apiGroups := []string{}
+	for _, group := range rule.apiGroups {
+		apiGroups = append(apiGroups, iff(group == "", "core", group))
+	}
+	row := "<tr>"
+	for _, values := range [][]string{rule.verbs, append(rule.resources, rule.nonResourceURLs...), apiGroups, rule.resourceNames} {
+		cell := escapeHTML(strings.Join(values, ", "))
+		if bold {
+			cell = "<b>" + cell + "</b>"
+		}
+		if scoped {
+			cell = `<font color="#2e7d32">` + cell + `</font>`
+		}
+		row += `<td align="left">` + cell + "</td>"
+	}
+	return row + "</tr>"
+}
+
+func rulesTableSpanningRow(html string) string {
+	return `<tr><td colspan="4" align="left">` + html + "</td></tr>"
+}
+
+// scopedLine colors a line of rules that's restricted to specific resourceNames, when using -highlight-scoped-rules
+func scopedLine(line string) string {
+	return `<font color="#2e7d32">` + line + `</font>`
+}
+
+func formatLabel(label string, highlight bool) interface{} {
+	if highlight {
+		return dot.HTML("<b>" + escapeHTML(label) + "</b>")
+	} else {
+		return label
+	}
+}
+
+func escapeHTML(str string) string {
+	str = strings.ReplaceAll(str, `&`, `&amp;`)
+	str = strings.ReplaceAll(str, `<`, `&lt;`)
+	str = strings.ReplaceAll(str, `>`, `&gt;`)
+	str = strings.ReplaceAll(str, ` `, `&nbsp;`)
+	str = strings.ReplaceAll(str, "\n", `<br/>`)
+	return str
+}
+
+func newSubjectToBindingEdge(subjectNode dot.Node, bindingNode dot.Node) dot.Edge {
+	return edge(subjectNode, bindingNode).Attr("dir", "back")
+}
+
+func newBindingToRoleEdge(bindingNode dot.Node, roleNode dot.Node) dot.Edge {
+	return edge(bindingNode, roleNode)
+}
+
+func newRoleToRulesEdge(roleNode dot.Node, rulesNode dot.Node) dot.Edge {
+	return edge(roleNode, rulesNode)
+}
+
+func newImpersonationEdge(subjectNode dot.Node, impersonatedNode dot.Node) dot.Edge {
+	return edge(subjectNode, impersonatedNode).
+		Attr("label", "can impersonate").
+		Attr("color", "red").
+		Attr("fontcolor", "red").
+		Attr("style", "dashed")
+}
+
+// newAggregationEdge connects a ClusterRole to one that includes all of its rules
+func newAggregationEdge(fromRoleNode dot.Node, toRoleNode dot.Node) dot.Edge {
+	return edge(fromRoleNode, toRoleNode).
+		Attr("label", "aggregated into").
+		Attr("color", "#808080").
+		Attr("fontcolor", "#808080").
+		Attr("style", "dashed")
+}
+
+func newSubjectToEffectiveRulesEdge(subjectNode dot.Node, rulesNode dot.Node) dot.Edge {
+	return edge(subjectNode, rulesNode).Attr("style", "dashed")
+}
+
+// edge creates a new edge between two nodes, but only if the edge doesn't exist yet. The dot library itself adds an
+// edge each time it's asked to, while the same binding, role or rules node is reached once per subject (and namespace)
+// it applies to, so all edges must be created through this function to avoid duplicate edges bloating the output.
+func edge(from dot.Node, to dot.Node) dot.Edge {
+	existingEdges := from.EdgesTo(to)
+	if len(existingEdges) == 0 {
+		return from.Edge(to)
+	} else {
+		return existingEdges[0]
+	}
+}
+
+func iff(condition bool, string1, string2 string) string {
+	if condition {
+		return string1
+	} else {
+		return string2
+	}
+}
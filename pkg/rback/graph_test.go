@@ -0,0 +1,106 @@
+package rback
+
+import (
+	"strings"
+	"testing"
+)
+
+// modelNodes returns the rendered nodes of the given kind in the given namespace
+func modelNodes(r *Rback, kind, namespace string) []modelNode {
+	nodes := []modelNode{}
+	for _, node := range r.model.nodes {
+		if node.kind == kind && node.namespace == namespace {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func TestRulesNodesOfRoleAndClusterRoleWithTheSameName(t *testing.T) {
+	r := testRbackFromItems(t, testConfig(t, "-quiet", "-show-legend=false"),
+		`{"kind": "Role", "metadata": {"name": "reader", "namespace": "app"},
+		  "rules": [{"apiGroups": [""], "resources": ["configmaps"], "verbs": ["get"]}]}`,
+		`{"kind": "ClusterRole", "metadata": {"name": "reader"},
+		  "rules": [{"apiGroups": [""], "resources": ["pods"], "verbs": ["list"]}]}`,
+		`{"kind": "RoleBinding", "metadata": {"name": "role-reader", "namespace": "app"},
+		  "roleRef": {"kind": "Role", "name": "reader"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "worker", "namespace": "app"}]}`,
+		`{"kind": "RoleBinding", "metadata": {"name": "clusterrole-reader", "namespace": "app"},
+		  "roleRef": {"kind": "ClusterRole", "name": "reader"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "worker", "namespace": "app"}]}`)
+	r.genGraph()
+
+	rules := modelNodes(r, "Rules", "app")
+	if len(rules) != 2 {
+		t.Fatalf("expected the rules of the Role and of the ClusterRole in two nodes in namespace app, got %v", rules)
+	}
+	for _, resource := range []string{"configmaps", "pods"} {
+		if !strings.Contains(rules[0].label+rules[1].label, resource) {
+			t.Errorf("expected a rules node with %s, got %v", resource, rules)
+		}
+	}
+	if strings.Contains(rules[0].label, "configmaps") == strings.Contains(rules[1].label, "configmaps") {
+		t.Errorf("expected the rules of the Role and of the ClusterRole to be in different nodes, got %v", rules)
+	}
+}
+
+func TestServiceAccountsWithTheSameNameInDifferentNamespaces(t *testing.T) {
+	r := testRbackFromItems(t, testConfig(t, "-quiet", "-show-legend=false"),
+		`{"kind": "ServiceAccount", "metadata": {"name": "default", "namespace": "a"}}`,
+		`{"kind": "ServiceAccount", "metadata": {"name": "default", "namespace": "b"}}`,
+		`{"kind": "ClusterRole", "metadata": {"name": "view"},
+		  "rules": [{"apiGroups": [""], "resources": ["pods"], "verbs": ["get"]}]}`,
+		`{"kind": "ClusterRoleBinding", "metadata": {"name": "view"},
+		  "roleRef": {"kind": "ClusterRole", "name": "view"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "default", "namespace": "a"},
+		               {"kind": "ServiceAccount", "name": "default", "namespace": "b"}]}`)
+	dot := r.genGraph().String()
+
+	for _, ns := range []string{"a", "b"} {
+		sas := modelNodes(r, "ServiceAccount", ns)
+		if len(sas) != 1 || sas[0].id != subjectNodeID("ServiceAccount", ns, "default") {
+			t.Errorf("expected ServiceAccount %s/default in namespace %s, got %v", ns, ns, sas)
+		}
+	}
+	if subjectNodeID("ServiceAccount", "a", "default") == subjectNodeID("ServiceAccount", "b", "default") {
+		t.Errorf("expected distinct node IDs for the default ServiceAccounts of namespaces a and b")
+	}
+	if count := strings.Count(dot, `label="default\n(ServiceAccount)"`); count != 2 {
+		t.Errorf("expected two default ServiceAccount nodes, got %d in:\n%s", count, dot)
+	}
+}
+
+func TestEdgesAreDeclaredOnce(t *testing.T) {
+	// the RoleBinding repeats its subject, and the ClusterRoleBinding binds ServiceAccounts of two namespaces, one of
+	// them twice
+	r := testRbackFromItems(t, testConfig(t, "-quiet", "-show-legend=false"),
+		`{"kind": "Role", "metadata": {"name": "reader", "namespace": "app"},
+		  "rules": [{"apiGroups": [""], "resources": ["configmaps"], "verbs": ["get"]}]}`,
+		`{"kind": "ClusterRole", "metadata": {"name": "view"},
+		  "rules": [{"apiGroups": [""], "resources": ["pods"], "verbs": ["get"]}]}`,
+		`{"kind": "RoleBinding", "metadata": {"name": "reader", "namespace": "app"},
+		  "roleRef": {"kind": "Role", "name": "reader"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "worker", "namespace": "app"},
+		               {"kind": "ServiceAccount", "name": "worker", "namespace": "app"}]}`,
+		`{"kind": "ClusterRoleBinding", "metadata": {"name": "view"},
+		  "roleRef": {"kind": "ClusterRole", "name": "view"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "worker", "namespace": "app"},
+		               {"kind": "ServiceAccount", "name": "worker", "namespace": "other"},
+		               {"kind": "ServiceAccount", "name": "worker", "namespace": "app"}]}`)
+	dot := r.genGraph().String()
+
+	// app/worker -> reader -> Role reader -> rules, app/worker and other/worker -> view -> ClusterRole view -> rules
+	const expected = 7
+	edges := map[string]bool{}
+	for _, line := range strings.Split(dot, "\n") {
+		if strings.Contains(line, "->") {
+			edges[strings.TrimSpace(line)] = true
+		}
+	}
+	if count := strings.Count(dot, "->"); count != expected || len(edges) != expected {
+		t.Errorf("expected %d distinct edges, got %d declarations of %d distinct edges in:\n%s", expected, count, len(edges), dot)
+	}
+	if len(r.model.edges) != expected {
+		t.Errorf("expected %d edges in the model, got %v", expected, r.model.edges)
+	}
+}
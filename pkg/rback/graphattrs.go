@@ -0,0 +1,34 @@
+package rback
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// graphAttrs holds the graph attributes given via -graph-attr, in the order they were given
+type graphAttrs [][2]string
+
+// graphAttrName matches the names of Graphviz attributes (e.g. newrank or fontname)
+var graphAttrName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (a *graphAttrs) String() string {
+	pairs := []string{}
+	for _, attr := range *a {
+		pairs = append(pairs, attr[0]+"="+attr[1])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds a key=value pair, so that -graph-attr can be given several times
+func (a *graphAttrs) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected key=value, but got %q", value)
+	}
+	if !graphAttrName.MatchString(parts[0]) {
+		return fmt.Errorf("invalid attribute name %q", parts[0])
+	}
+	*a = append(*a, [2]string{parts[0], parts[1]})
+	return nil
+}
@@ -0,0 +1,119 @@
+package rback
+
+import (
+	"html/template"
+	"io"
+)
+
+// htmlGraph is the JSON representation of the graph model that's embedded into the HTML page
+type htmlGraph struct {
+	Title string      `json:"title"`
+	Nodes []htmlNode  `json:"nodes"`
+	Edges [][2]string `json:"edges"`
+}
+
+type htmlNode struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Label     string `json:"label"`
+}
+
+// writeHTML writes a self-contained HTML page for exploring the nodes and edges recorded by genGraph: each subject
+// (and any other node without incoming edges) can be expanded to show its bindings, their roles and the access rules.
+// The model is embedded as JSON and turned into collapsible elements by a small script, without any external resources.
+func (r *Rback) writeHTML(w io.Writer) error {
+	graph := htmlGraph{Title: iff(r.config.title != "", r.config.title, "rback"), Nodes: []htmlNode{}, Edges: [][2]string{}}
+	for _, n := range r.model.nodes {
+		graph.Nodes = append(graph.Nodes, htmlNode{ID: n.id, Kind: n.kind, Namespace: n.namespace, Label: n.label})
+	}
+	for _, e := range r.model.edges {
+		graph.Edges = append(graph.Edges, [2]string{e.source, e.target})
+	}
+	return htmlTemplate.Execute(w, graph)
+}
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+details { margin-left: 1.5em; }
+summary { cursor: pointer; padding: 2px 0; }
+.kind { color: #666; font-size: smaller; }
+.ServiceAccount, .User, .Group { color: #2f6de1; font-weight: bold; }
+.RoleBinding, .ClusterRoleBinding { color: #b38f00; }
+.Role, .ClusterRole { color: #cc7a00; }
+pre { margin: 2px 0 2px 3em; }
+#controls { margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div id="controls">
+<input id="filter" type="search" placeholder="Filter subjects">
+<button id="expand">Expand all</button>
+<button id="collapse">Collapse all</button>
+</div>
+<div id="tree"></div>
+<script>
+var graph = {{.}};
+var nodes = {}, children = {}, hasParent = {};
+graph.nodes.forEach(function (n) { nodes[n.id] = n; children[n.id] = []; });
+graph.edges.forEach(function (e) {
+  if (nodes[e[0]] && nodes[e[1]]) { children[e[0]].push(e[1]); hasParent[e[1]] = true; }
+});
+
+function describe(n) {
+  var summary = document.createElement("summary");
+  var name = document.createElement("span");
+  name.className = n.kind;
+  name.textContent = n.label;
+  var kind = document.createElement("span");
+  kind.className = "kind";
+  kind.textContent = " " + n.kind + (n.namespace ? " in " + n.namespace : "");
+  summary.appendChild(name);
+  summary.appendChild(kind);
+  return summary;
+}
+
+// render nests the children of a node into it, stopping at nodes already on the path (impersonation may form cycles)
+function render(id, path) {
+  var n = nodes[id];
+  if (n.kind === "Rules" || n.kind === "EffectiveRules") {
+    var pre = document.createElement("pre");
+    pre.textContent = n.label;
+    return pre;
+  }
+  var details = document.createElement("details");
+  details.appendChild(describe(n));
+  path[id] = true;
+  children[id].forEach(function (child) {
+    if (!path[child]) { details.appendChild(render(child, path)); }
+  });
+  delete path[id];
+  return details;
+}
+
+var tree = document.getElementById("tree");
+graph.nodes.forEach(function (n) {
+  if (!hasParent[n.id]) { tree.appendChild(render(n.id, {})); }
+});
+
+function setOpen(open) {
+  document.querySelectorAll("details").forEach(function (d) { d.open = open; });
+}
+document.getElementById("expand").onclick = function () { setOpen(true); };
+document.getElementById("collapse").onclick = function () { setOpen(false); };
+document.getElementById("filter").oninput = function () {
+  var text = this.value.toLowerCase();
+  tree.childNodes.forEach(function (d) {
+    d.style.display = d.textContent.toLowerCase().indexOf(text) >= 0 ? "" : "none";
+  });
+};
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,52 @@
+package rback
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/dot"
+)
+
+// renderIgnoredSummaries draws a single summary node for each rendered subject that is bound by bindings matching
+// -ignore-prefixes, for -collapse-ignored. This shows that these bindings exist, without the clutter of each of them.
+func (r *Rback) renderIgnoredSummaries(g *dot.Graph) {
+	counts := map[KindNamespacedName]int{}
+	for ns, bindings := range r.permissions.IgnoredRoleBindings {
+		if ns != "" && !r.namespaceSelected(ns) {
+			continue
+		}
+		for _, binding := range bindings {
+			for _, subject := range binding.subjects {
+				counts[subject]++
+			}
+		}
+	}
+
+	subjects := []KindNamespacedName{}
+	for subject := range counts {
+		subjects = append(subjects, subject)
+	}
+	sort.Slice(subjects, func(i, j int) bool {
+		return subjects[i].kind+subjects[i].qualifiedName() < subjects[j].kind+subjects[j].qualifiedName()
+	})
+
+	for _, subject := range subjects {
+		subjectID := subjectNodeID(subject.kind, subject.namespace, subject.name)
+		if !r.model.nodeIDs[subjectID] {
+			continue // don't add subjects just because of ignored bindings
+		}
+		gns := r.newNamespaceSubgraph(g, subject.namespace)
+		subjectNode := r.newSubjectNode(gns, subject.kind, subject.namespace, subject.name)
+		summaryID := ignoredBindingsNodeID(subjectID)
+		summaryNode := newIgnoredBindingsNode(gns, summaryID, r.ignoredBindingsLabel(counts[subject]))
+		newSubjectToBindingEdge(subjectNode, summaryNode)
+		r.model.addNode(summaryID, "IgnoredBindings", subject.namespace, r.ignoredBindingsLabel(counts[subject]))
+		r.model.addEdge(subjectID, summaryID)
+	}
+}
+
+// ignoredBindingsLabel describes the number of collapsed bindings, e.g. "system:* (3 hidden)"
+func (r *Rback) ignoredBindingsLabel(count int) string {
+	return fmt.Sprintf("%s* (%d hidden)", strings.Join(r.config.ignoredPrefixes, "*, "), count)
+}
@@ -0,0 +1,55 @@
+package rback
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// imageFormats are the extensions of -o for which the graph is rendered into an image by Graphviz' dot, instead of
+// writing it in one of the formats
+var imageFormats = []string{"png", "svg", "pdf", "jpg"}
+
+// inferFormat returns the format implied by the extension of the output path (e.g. "json" for rbac.json), and the
+// image format to render it into, if it's an image; ok is false for unknown extensions
+func inferFormat(path string) (format, imageFormat string, ok bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gv" {
+		return formatDot, "", true
+	}
+	for format, formatExt := range fileExtensions {
+		if ext == formatExt {
+			return format, "", true
+		}
+	}
+	if contains(imageFormats, strings.TrimPrefix(ext, ".")) {
+		return formatDot, strings.TrimPrefix(ext, "."), true
+	}
+	return "", "", false
+}
+
+// writeImage renders the graph into an image by running Graphviz' dot, which must be on the PATH
+func (r *Rback) writeImage(w io.Writer) error {
+	dotPath, err := exec.LookPath("dot")
+	if err != nil {
+		return fmt.Errorf("rendering a %s image requires Graphviz' dot on the PATH (or write the graph to a .dot file)", r.config.imageFormat)
+	}
+	var graph, stderr bytes.Buffer
+	if err := r.writeOutput(&graph); err != nil {
+		return err
+	}
+	timed("Rendering image", func() {
+		cmd := exec.Command(dotPath, "-T"+r.config.imageFormat)
+		cmd.Stdin = &graph
+		cmd.Stdout = w
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+	})
+	if err != nil {
+		return fmt.Errorf("dot failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
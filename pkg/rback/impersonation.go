@@ -0,0 +1,90 @@
+package rback
+
+import (
+	"github.com/emicklei/dot"
+)
+
+// impersonatableKinds maps the resources that can be impersonated to the kind of the subject they represent
+var impersonatableKinds = map[string]string{
+	"users":           "User",
+	"groups":          "Group",
+	"serviceaccounts": "ServiceAccount",
+}
+
+// impersonatedKinds returns the kinds of subjects the rule allows impersonating (if any)
+func (rule *Rule) impersonatedKinds() []string {
+	if !contains(rule.verbs, "impersonate") && !contains(rule.verbs, "*") {
+		return nil
+	}
+	if len(rule.apiGroups) > 0 && !contains(rule.apiGroups, "") && !contains(rule.apiGroups, "*") {
+		return nil // users, groups and serviceaccounts are in the core API group
+	}
+	kinds := []string{}
+	for _, resource := range []string{"users", "groups", "serviceaccounts"} {
+		if contains(rule.resources, resource) || contains(rule.resources, "*") {
+			kinds = append(kinds, impersonatableKinds[resource])
+		}
+	}
+	return kinds
+}
+
+// renderImpersonation draws an edge from each rendered subject that can impersonate other identities to these identities.
+// If the identities aren't restricted via resourceNames, the edge points to a node representing any identity of that kind.
+func (r *Rback) renderImpersonation(g *dot.Graph) {
+	grants := r.findGrants(func(rule Rule) bool {
+		return len(rule.impersonatedKinds()) > 0
+	})
+	for _, grant := range grants {
+		subjectID := subjectNodeID(grant.subject.kind, grant.subject.namespace, grant.subject.name)
+		if !r.model.nodeIDs[subjectID] || !r.onPath(grant.subject, grant.binding) {
+			continue // only show impersonation by subjects that are rendered anyway
+		}
+		subjectNode := r.newSubjectNode(r.newNamespaceSubgraph(g, grant.subject.namespace), grant.subject.kind, grant.subject.namespace, grant.subject.name)
+
+		// ServiceAccounts are namespaced, so a RoleBinding only allows impersonating those in its own namespace
+		scope := grant.binding.namespace
+		for _, kind := range grant.rule.impersonatedKinds() {
+			if len(grant.rule.resourceNames) == 0 {
+				gns := r.newNamespaceSubgraph(g, iff(kind == "ServiceAccount", scope, ""))
+				targetNode := newAnyIdentityNode(gns, kind, scope)
+				newImpersonationEdge(subjectNode, targetNode)
+				targetID := anyIdentityNodeID(kind, scope)
+				r.model.addNode(targetID, "Any"+kind, scope, anyIdentityLabel(kind, scope))
+				r.model.addEdge(subjectID, targetID)
+				continue
+			}
+			for _, name := range grant.rule.resourceNames {
+				for _, target := range r.impersonationTargets(kind, scope, name) {
+					targetNode := r.newSubjectNode(r.newNamespaceSubgraph(g, target.namespace), kind, target.namespace, target.name)
+					newImpersonationEdge(subjectNode, targetNode)
+					r.model.addEdge(subjectID, subjectNodeID(kind, target.namespace, target.name))
+				}
+			}
+		}
+	}
+}
+
+// impersonationTargets returns the identities with the given name that can be impersonated. For ServiceAccounts granted
+// cluster-wide, that's the ServiceAccount with this name in every namespace.
+func (r *Rback) impersonationTargets(kind, scope, name string) []NamespacedName {
+	if kind != "ServiceAccount" {
+		return []NamespacedName{{"", name}}
+	}
+	if scope != "" {
+		return []NamespacedName{{scope, name}}
+	}
+	targets := []NamespacedName{}
+	for ns, sas := range r.permissions.ServiceAccounts {
+		if _, found := sas[name]; found && r.namespaceSelected(ns) {
+			targets = append(targets, NamespacedName{ns, name})
+		}
+	}
+	return targets
+}
+
+func anyIdentityLabel(kind, scope string) string {
+	if kind == "ServiceAccount" && scope != "" {
+		return "any ServiceAccount in " + scope
+	}
+	return "any " + kind
+}
@@ -0,0 +1,65 @@
+package rback
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONSchemaVersion is the version of the structures written by -format json, which are described by the JSON Schemas
+// in schema/. It's only incremented for incompatible changes (e.g. removing or renaming fields), not for additions.
+const JSONSchemaVersion = 1
+
+// JSONGraph is the structure written by -format json (see schema/graph.v1.json), for programs that decode it
+type JSONGraph struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	Nodes         []JSONNode `json:"nodes"`
+	Edges         []JSONEdge `json:"edges"`
+}
+
+// JSONNode is a rendered node, e.g. a subject, binding or role, which the edges refer to by its ID
+type JSONNode struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Namespace string     `json:"namespace"`
+	Label     string     `json:"label"`
+	Rules     []JSONRule `json:"rules,omitempty"` // only for Roles and ClusterRoles
+}
+
+// JSONRule is an access rule of a role, as defined in it
+type JSONRule struct {
+	Verbs           []string `json:"verbs"`
+	APIGroups       []string `json:"apiGroups"`
+	Resources       []string `json:"resources"`
+	ResourceNames   []string `json:"resourceNames"`
+	NonResourceURLs []string `json:"nonResourceURLs"`
+}
+
+// JSONEdge connects the nodes with the IDs Source and Target, e.g. a subject to its binding
+type JSONEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// writeJSON writes the nodes and edges recorded by genGraph as JSON, along with the access rules of the roles, for
+// processing by other tools
+func (r *Rback) writeJSON(w io.Writer) error {
+	graph := JSONGraph{SchemaVersion: JSONSchemaVersion, Nodes: []JSONNode{}, Edges: []JSONEdge{}}
+	for _, n := range r.model.nodes {
+		node := JSONNode{ID: n.id, Kind: n.kind, Namespace: n.namespace, Label: n.label}
+		if n.kind == "Role" || n.kind == "ClusterRole" {
+			if role, found := r.lookupRole(NamespacedName{n.namespace, n.label}); found {
+				node.Rules = []JSONRule{}
+				for _, rule := range role.rules {
+					node.Rules = append(node.Rules, JSONRule{rule.verbs, rule.apiGroups, rule.resources, rule.resourceNames, rule.nonResourceURLs})
+				}
+			}
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for _, e := range r.model.edges {
+		graph.Edges = append(graph.Edges, JSONEdge{e.source, e.target})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(graph)
+}
@@ -0,0 +1,197 @@
+package rback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// jsonSchema validates JSON against the subset of JSON Schema (draft-07) used by the schemas in schema/. It's stricter
+// than JSON Schema in that properties missing from the schema are invalid, so that the output can't silently drift
+// from its schema.
+type jsonSchema struct {
+	root map[string]interface{}
+}
+
+func loadJSONSchema(t *testing.T, path string) jsonSchema {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return jsonSchema{root}
+}
+
+// validate returns the problems of the value at the given path (e.g. "$.nodes[0]")
+func (s jsonSchema) validate(schema map[string]interface{}, value interface{}, path string) []string {
+	problems := []string{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+	keywords := []string{}
+	for keyword := range schema {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		constraint := schema[keyword]
+		switch keyword {
+		case "$schema", "$id", "title", "description", "definitions":
+		case "$ref":
+			ref := constraint.(string)
+			definition, ok := s.root["definitions"].(map[string]interface{})[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+			if !strings.HasPrefix(ref, "#/definitions/") || !ok {
+				problem("unknown $ref %s", ref)
+				continue
+			}
+			problems = append(problems, s.validate(definition, value, path)...)
+		case "type":
+			if !jsonTypeMatches(constraint.(string), value) {
+				problem("expected %s, got %v", constraint, value)
+			}
+		case "const":
+			if !reflect.DeepEqual(constraint, value) {
+				problem("expected %v, got %v", constraint, value)
+			}
+		case "enum":
+			found := false
+			for _, allowed := range constraint.([]interface{}) {
+				found = found || reflect.DeepEqual(allowed, value)
+			}
+			if !found {
+				problem("expected one of %v, got %v", constraint, value)
+			}
+		case "minimum":
+			if number, ok := value.(float64); ok && number < constraint.(float64) {
+				problem("expected at least %v, got %v", constraint, value)
+			}
+		case "required":
+			if object, ok := value.(map[string]interface{}); ok {
+				for _, name := range constraint.([]interface{}) {
+					if _, found := object[name.(string)]; !found {
+						problem("missing required property %s", name)
+					}
+				}
+			}
+		case "properties":
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			properties := constraint.(map[string]interface{})
+			names := []string{}
+			for name := range object {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				property, declared := properties[name].(map[string]interface{})
+				if !declared {
+					problem("property %s isn't in the schema", name)
+					continue
+				}
+				problems = append(problems, s.validate(property, object[name], path+"."+name)...)
+			}
+		case "items":
+			if array, ok := value.([]interface{}); ok {
+				for i, item := range array {
+					problems = append(problems, s.validate(constraint.(map[string]interface{}), item, fmt.Sprintf("%s[%d]", path, i))...)
+				}
+			}
+		default:
+			problem("unsupported schema keyword %s", keyword)
+		}
+	}
+	return problems
+}
+
+func jsonTypeMatches(jsonType string, value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return jsonType == "object"
+	case []interface{}:
+		return jsonType == "array"
+	case string:
+		return jsonType == "string"
+	case bool:
+		return jsonType == "boolean"
+	case float64:
+		return jsonType == "number" || (jsonType == "integer" && v == math.Trunc(v))
+	case nil:
+		return jsonType == "null"
+	}
+	return false
+}
+
+// assertMatchesSchema asserts that the JSON output is valid according to the schema file
+func assertMatchesSchema(t *testing.T, schemaPath string, output []byte) {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal(output, &value); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	schema := loadJSONSchema(t, schemaPath)
+	for _, problem := range schema.validate(schema.root, value, "$") {
+		t.Errorf("%s: %s", schemaPath, problem)
+	}
+}
+
+// schemaTestInputs cover all node kinds, findings and statistics that the examples can produce
+var schemaTestInputs = []string{
+	"../../examples/cross-namespace-roleref.json",
+	"../../examples/unusual-characters.json",
+	"../../examples/role-usage.json",
+	"../../examples/serviceaccount-groups.json",
+}
+
+func TestJSONMatchesSchema(t *testing.T) {
+	config := testConfig(t, "-quiet", "-format", "json", "-effective-rules", "-show-impersonation", "-collapse-ignored",
+		"-show-sa-secrets")
+	r := testRback(t, config, schemaTestInputs...)
+	var output bytes.Buffer
+	if err := r.writeJSON(&output); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesSchema(t, "../../schema/graph.v1.json", output.Bytes())
+}
+
+func TestJSONReportMatchesSchema(t *testing.T) {
+	r := testRback(t, testConfig(t, "-quiet", "-report-only", "-format", "json"), schemaTestInputs...)
+	sections := r.reportSections()
+	if len(sections) == 0 {
+		t.Fatal("expected findings in the report")
+	}
+	var output bytes.Buffer
+	if err := writeJSONReport(&output, sections); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesSchema(t, "../../schema/report.v1.json", output.Bytes())
+}
+
+func TestJSONStatsMatchSchema(t *testing.T) {
+	r := testRback(t, testConfig(t, "-quiet", "-stats", "-format", "json"), schemaTestInputs...)
+	var output bytes.Buffer
+	if err := r.writeStats(&output); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesSchema(t, "../../schema/stats.v1.json", output.Bytes())
+}
+
+func TestJSONRoleUsageMatchesSchema(t *testing.T) {
+	r := testRback(t, testConfig(t, "-quiet", "-format", "json"), schemaTestInputs...)
+	var output bytes.Buffer
+	if err := r.writeRoleUsage(&output); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesSchema(t, "../../schema/role-usage.v1.json", output.Bytes())
+}
@@ -0,0 +1,78 @@
+package rback
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/emicklei/dot"
+)
+
+// nodeLabelData holds the fields available to -node-label-template
+type nodeLabelData struct {
+	Kind         string // e.g. ServiceAccount, RoleBinding or ClusterRole
+	Namespace    string // empty for cluster-scoped resources, Users and Groups
+	Name         string
+	BindingCount int // the number of bindings of a subject or role (1 for bindings themselves)
+}
+
+// applyLabelTemplate replaces the default label of a subject, binding or role node with the one rendered from
+// -node-label-template, if set
+func (r *Rback) applyLabelTemplate(node dot.Node, kind, ns, name string, highlight bool) {
+	if label, ok := r.templatedLabel(kind, ns, name); ok {
+		node.Attr("label", formatLabel(label, highlight))
+	}
+}
+
+// subjectLabel returns the label of a subject node, i.e. the default "name\n(kind)" unless -node-label-template is set
+func (r *Rback) subjectLabel(kind, ns, name string) string {
+	if label, ok := r.templatedLabel(kind, ns, name); ok {
+		return label
+	}
+	if groupNs, ok := serviceAccountsGroupNamespace(KindNamespacedName{kind, NamespacedName{ns, name}}); ok {
+		return serviceAccountsGroupLabel(groupNs)
+	}
+	return fmt.Sprintf("%s\n(%s)", name, kind)
+}
+
+func (r *Rback) templatedLabel(kind, ns, name string) (string, bool) {
+	if r.config.nodeLabelTemplate == nil {
+		return "", false
+	}
+	data := nodeLabelData{Kind: kind, Namespace: ns, Name: name, BindingCount: r.bindingCount(kind, ns, name)}
+	var label bytes.Buffer
+	if err := r.config.nodeLabelTemplate.Execute(&label, data); err != nil {
+		warnf("Can't render the label of %s %s with -node-label-template: %v", kind, name, err)
+		return "", false
+	}
+	return label.String(), true
+}
+
+func (r *Rback) bindingCount(kind, ns, name string) int {
+	switch kind {
+	case "RoleBinding", "ClusterRoleBinding":
+		return 1
+	}
+	count := 0
+	for _, bindings := range r.permissions.RoleBindings {
+		for _, binding := range bindings {
+			switch kind {
+			case "Role", "ClusterRole":
+				if binding.role == (NamespacedName{ns, name}) {
+					count++
+				}
+			default:
+				if binding.hasSubject(KindNamespacedName{kind, NamespacedName{ns, name}}) {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// parseNodeLabelTemplate parses -node-label-template, in which "\n" can be used for line breaks
+func parseNodeLabelTemplate(text string) (*template.Template, error) {
+	return template.New("node-label").Parse(strings.Replace(text, `\n`, "\n", -1))
+}
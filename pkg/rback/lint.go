@@ -0,0 +1,166 @@
+package rback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// commandValidate checks the input manifests for structural problems without rendering them, e.g. before applying
+// them, and exits with a non-zero status if there are errors
+const commandValidate = "validate"
+
+// the severities of the problems found by the validate command
+const (
+	lintError   = "error"   // the API server would reject the resource, or it can't work as intended
+	lintWarning = "warning" // the resource is valid, but likely not what was meant
+)
+
+// lintProblem is a structural problem of a resource in the input
+type lintProblem struct {
+	location string // FILE:LINE of the resource, or just FILE if the input can't be parsed at all
+	severity string
+	resource string // e.g. "RoleBinding ns/name"
+	message  string
+}
+
+func (p lintProblem) String() string {
+	if p.resource == "" {
+		return fmt.Sprintf("%s: %s: %s", p.location, p.severity, p.message)
+	}
+	return fmt.Sprintf("%s: %s: %s %s", p.location, p.severity, p.resource, p.message)
+}
+
+// lintInputs checks each resource of the inputs given via -f (or stdin) on its own, then parses them like for rendering
+// and adds the problems found by the analysis of all of them together: bindings of roles that none of the inputs
+// define, and ServiceAccounts that aren't bound to any role. The problems of single resources come first, in the order
+// of the inputs.
+func (r *Rback) lintInputs() ([]lintProblem, error) {
+	problems := []lintProblem{}
+	locations := map[string]string{} // the location of each resource by description, for finding duplicates
+	lint := func(name string, data []byte) {
+		items, itemKind, err := kubeItems(data)
+		if err != nil {
+			problems = append(problems, lintProblem{location: name, severity: lintError, message: err.Error()})
+			return
+		}
+		problems = append(problems, lintItems(name, data, items, itemKind, locations)...)
+		if err := r.parseRBAC(bytes.NewReader(data)); err != nil {
+			problems = append(problems, lintProblem{location: name, severity: lintError, message: err.Error()})
+		}
+	}
+	if len(r.config.inputFiles) == 0 {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Can't read stdin: %v", err)
+		}
+		lint("stdin", data)
+	}
+	for _, inputFile := range r.config.inputFiles {
+		data, err := ioutil.ReadFile(inputFile)
+		if err != nil {
+			return nil, fmt.Errorf("Can't read file %s: %v", inputFile, err)
+		}
+		lint(inputFile, data)
+	}
+
+	_, dangling := r.findDanglingBindings()
+	for _, d := range dangling {
+		if d.role.name == "" {
+			continue // already reported as missing its roleRef
+		}
+		resource := iff(d.binding.namespace == "", "ClusterRoleBinding", "RoleBinding") + " " + d.binding.qualifiedName()
+		role := finding{role: d.role}.roleDescription()
+		problems = append(problems, lintProblem{locations[resource], lintWarning, resource,
+			fmt.Sprintf("references %s, which isn't defined by the input (so it must exist in the cluster)", role)})
+	}
+	for _, sa := range r.findOrphanServiceAccounts() {
+		resource := "ServiceAccount " + sa.qualifiedName()
+		problems = append(problems, lintProblem{locations[resource], lintWarning, resource, "isn't bound to any role"})
+	}
+	return problems, nil
+}
+
+// lintItems checks the items of the input with the given name on their own, and against the resources of the inputs
+// before it for duplicates
+func lintItems(name string, data []byte, items []json.RawMessage, itemKind string, locations map[string]string) []lintProblem {
+	problems := []lintProblem{}
+	offset := 0
+	for i, rawItem := range items {
+		// the raw items are verbatim copies of the input, so they can be found in it to tell their line
+		location := name
+		if index := bytes.Index(data[offset:], rawItem); index >= 0 {
+			location = fmt.Sprintf("%s:%d", name, bytes.Count(data[:offset+index], []byte("\n"))+1)
+			offset += index + len(rawItem)
+		}
+		var item kubeObject
+		if err := json.Unmarshal(rawItem, &item); err != nil {
+			problems = append(problems, lintProblem{location: location, severity: lintError,
+				message: fmt.Sprintf("item %d is not a valid resource: %v", i, err)})
+			continue
+		}
+		if item.Kind == "" {
+			item.Kind = itemKind
+		}
+		resource := item.Kind + " " + NamespacedName{item.Metadata.Namespace, item.Metadata.Name}.qualifiedName()
+		if item.Metadata.Name == "" {
+			resource = fmt.Sprintf("%s (item %d)", item.Kind, i)
+		}
+		problem := func(severity, format string, args ...interface{}) {
+			problems = append(problems, lintProblem{location, severity, resource, fmt.Sprintf(format, args...)})
+		}
+
+		if item.Metadata.Name == "" {
+			problem(lintError, "has no metadata.name")
+		} else if previous, ok := locations[resource]; ok {
+			problem(lintError, "is defined again (first at %s), so only the last definition takes effect", previous)
+		} else {
+			locations[resource] = location
+		}
+
+		switch item.Kind {
+		case "Role", "ClusterRole":
+			for j, rule := range item.Rules {
+				if len(rule.Verbs) == 0 {
+					problem(lintError, "has no verbs in rule %d", j)
+				}
+				if len(rule.Resources) == 0 && len(rule.NonResourceURLs) == 0 {
+					problem(lintError, "has neither resources nor nonResourceURLs in rule %d", j)
+				}
+				if len(rule.NonResourceURLs) > 0 && item.Kind == "Role" {
+					problem(lintError, "has nonResourceURLs in rule %d, which only ClusterRoles can grant", j)
+				}
+			}
+		case "RoleBinding", "ClusterRoleBinding":
+			switch {
+			case item.RoleRef.Kind == "" && item.RoleRef.Name == "":
+				problem(lintError, "has no roleRef")
+			case item.RoleRef.Name == "":
+				problem(lintError, "has no roleRef name")
+			case item.RoleRef.Kind != "Role" && item.RoleRef.Kind != "ClusterRole":
+				problem(lintError, "references role %s of unknown kind %q (expected Role or ClusterRole)", item.RoleRef.Name, item.RoleRef.Kind)
+			case item.RoleRef.Kind == "Role" && item.Kind == "ClusterRoleBinding":
+				problem(lintError, "references Role %s, but ClusterRoleBindings can only reference ClusterRoles", item.RoleRef.Name)
+			}
+			if len(item.Subjects) == 0 {
+				problem(lintWarning, "has no subjects")
+			}
+			for j, s := range item.Subjects {
+				switch {
+				case s.Kind == "":
+					problem(lintError, "has no kind in subject %d", j)
+				case s.Kind != "User" && s.Kind != "Group" && s.Kind != "ServiceAccount":
+					problem(lintError, "has subject %d of unknown kind %q (expected User, Group or ServiceAccount)", j, s.Kind)
+				case s.Kind == "ServiceAccount" && s.Namespace == "" && item.Kind == "ClusterRoleBinding":
+					problem(lintError, "has no namespace in ServiceAccount subject %d", j)
+				}
+				if s.Name == "" {
+					problem(lintError, "has no name in subject %d", j)
+				}
+			}
+		}
+	}
+	return problems
+}
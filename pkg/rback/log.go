@@ -0,0 +1,76 @@
+package rback
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// stdout is reserved for the rendered graph, so all diagnostics go to stderr
+type verbosity int
+
+const (
+	verbosityQuiet verbosity = iota
+	verbosityNormal
+	verbosityVerbose
+)
+
+// logVerbosity is the only package-level state; Main sets it once at startup, before any rendering starts, and the
+// exported API (see RenderTo) leaves it alone
+var logVerbosity = verbosityNormal
+
+func setVerbosity(verbose, quiet bool) {
+	switch {
+	case quiet:
+		logVerbosity = verbosityQuiet
+	case verbose:
+		logVerbosity = verbosityVerbose
+	default:
+		logVerbosity = verbosityNormal
+	}
+}
+
+// debugf logs details that are only of interest when running with -v
+func debugf(format string, args ...interface{}) {
+	if logVerbosity >= verbosityVerbose {
+		logf(format, args...)
+	}
+}
+
+// warnf logs problems that don't prevent rback from rendering a graph (suppressed by -quiet)
+func warnf(format string, args ...interface{}) {
+	if logVerbosity >= verbosityNormal {
+		logf(format, args...)
+	}
+}
+
+// infof logs status messages like the summary of the rendered graph (suppressed by -quiet)
+func infof(format string, args ...interface{}) {
+	if logVerbosity >= verbosityNormal {
+		logf(format, args...)
+	}
+}
+
+// errorf logs fatal problems and is never suppressed
+func errorf(format string, args ...interface{}) {
+	logf(format, args...)
+}
+
+// progressf reports the completion of a phase when running with -progress, so that users of large clusters can tell
+// which phase is slow
+func (r *Rback) progressf(format string, args ...interface{}) {
+	if r.config.progress {
+		logf(format, args...)
+	}
+}
+
+func logf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// timed runs fn and logs how long it took in verbose mode
+func timed(phase string, fn func()) {
+	start := time.Now()
+	fn()
+	debugf("%s took %v", phase, time.Since(start))
+}
@@ -0,0 +1,643 @@
+package rback
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/emicklei/dot"
+)
+
+// Rback renders the RBAC resources in permissions according to config. Instances don't share any mutable state, so
+// separate instances can render concurrently (e.g. one per request in a server). A single instance must not, since
+// genGraph keeps the state of the current rendering in it.
+type Rback struct {
+	config      Config
+	permissions Permissions
+
+	// state of the current rendering, reset by genGraph
+	focused *focusedResources       // only set when rendering with -focus
+	model   graphModel              // the nodes and edges rendered by genGraph
+	orphans map[NamespacedName]bool // only set when rendering with -mark-orphans
+
+	pathGrants      map[pathGrant]bool    // only set when rendering with -path-to
+	rulesNodes      map[string]*dot.Node  // the rules nodes rendered so far by ID, nil for roles without shown rules
+	namespaceGraphs map[string]*dot.Graph // the namespace subgraphs rendered so far, by namespace
+
+	// the effective rules (and permission counts) of each subject computed so far, and the bindings of each subject
+	// they're computed from; reset by genGraph and parseRBAC
+	effective         map[KindNamespacedName][]scopedRule
+	permissionCounts  map[KindNamespacedName]int
+	bindingsByGrantee map[KindNamespacedName][]Binding
+
+	rawInputs []*rawInput // only kept when writing a -bundle
+}
+
+type Config struct {
+	command                  string // a subcommand that doesn't render a graph, e.g. orphan-sa
+	inputFiles               []string
+	bundle                   string
+	fromBundle               string
+	bundleContext            string
+	contexts                 []string // the kubeconfig contexts to fetch the input from, instead of reading it
+	kubectl                  string
+	kubectlFlags             []string // the connection flags (e.g. --server) of the kubectl commands run for -contexts and -watch
+	format                   string
+	splitBy                  string
+	clusterWideOnly          bool // only renders cluster-wide objects, for the cluster-wide file of -split-by
+	markdownSections         []string
+	outputPath               string
+	imageFormat              string // inferred from the extension of -o, e.g. "png"
+	gzip                     bool
+	watch                    time.Duration
+	showRules                bool
+	annotateRulesScope       bool
+	rulesStyle               string
+	rulesFormat              string
+	nodeLabelTemplate        *template.Template // nil unless -node-label-template is set
+	noRulesFor               []string
+	bindingsOnly             bool
+	writesOnly               bool
+	resourceName             string
+	hiddenResources          []string
+	hiddenVerbs              []string
+	readOnlyVerbs            []string
+	compact                  bool
+	edgeLabel                string
+	highlightScopedRules     bool
+	effectiveRules           bool
+	mergeClusterRoles        bool
+	mergeRules               bool
+	showDefaultRoleHierarchy bool
+	colorNamespaces          bool
+	groupByLabel             string // the label of Namespaces to group them by
+	showAutomount            bool
+	showPermissionCount      bool
+	showSASecrets            bool
+	markOrphans              bool
+	hideDefaultSA            bool
+	showImpersonation        bool
+	pathTo                   string
+	reportWildcards          bool
+	reportSecretReaders      bool
+	reportSecretWriters      bool
+	reportImpersonation      bool
+	reportPublicAccess       bool
+	reportEscalation         bool
+	reportCrossNamespace     bool
+	reportOrphans            bool
+	reportOnly               bool
+	stats                    bool
+	failOn                   string // the severity of findings to exit with a non-zero status for
+	failOnEmpty              bool
+	suppressedCodes          []string
+	riskPolicy               map[string]string // the action per finding code, only set with -fail-on-risky
+	showEmptyBindings        bool
+	title                    string
+	caption                  string
+	rankSep                  float64
+	nodeSep                  float64
+	splines                  string
+	orientation              string
+	graphAttrs               graphAttrs
+	urlTemplate              string
+	showLegend               bool
+	legendOnly               bool
+	legendPresentOnly        bool
+	overview                 bool
+	namespaces               []string
+	subjectKinds             []string
+	since                    time.Duration
+	onlyAnnotated            annotationSelector
+	now                      time.Time // the time -since is relative to
+	excludedNamespaces       []string
+	ignoredPrefixes          []string
+	collapseIgnored          bool
+	resourceKind             string
+	resourceNames            []string
+	whoCan                   WhoCan
+	identity                 identity // the identity given to the whoami command
+	focus                    Focus
+	verbose                  bool
+	quiet                    bool
+	validate                 bool // hidden, see hiddenFlags
+	progress                 bool
+	maxNodes                 int
+}
+
+type WhoCan struct {
+	verb, resourceKind, resourceName string
+	apiGroup                         string // only set if the resource was qualified with its group
+	showMatchedOnly                  bool
+}
+
+// Main runs the rback command with the arguments in os.Args, exiting with a non-zero status on errors and findings
+func Main() {
+	config := parseConfigFromArgs()
+	setVerbosity(config.verbose, config.quiet)
+	rback := Rback{config: config}
+
+	if config.legendOnly {
+		fmt.Println(rback.genLegend().String())
+		return
+	}
+
+	if config.watch > 0 {
+		rback.watch()
+		return
+	}
+
+	if len(config.contexts) > 0 {
+		var err error
+		if config.outputPath != "" {
+			err = rback.writeFile(config.outputPath)
+		} else {
+			err = rback.writeMaybeCompressed(os.Stdout)
+		}
+		if err != nil {
+			errorf("Can't write the graph of contexts %s: %v", strings.Join(config.contexts, ", "), err)
+			os.Exit(-1)
+		}
+		return
+	}
+
+	if config.command == commandValidate {
+		rback.validateInputs()
+		return
+	}
+
+	if err := rback.parseInputs(); err != nil {
+		errorf("%v", err)
+		os.Exit(-1)
+	}
+
+	if config.bundle != "" {
+		if err := rback.writeBundle(config.bundle); err != nil {
+			errorf("Can't write bundle %s: %v", config.bundle, err)
+			os.Exit(-1)
+		}
+		infof("Wrote the inputs and the graph to bundle %s", config.bundle)
+	}
+
+	if config.command == commandOrphanSA {
+		for _, sa := range rback.findOrphanServiceAccounts() {
+			fmt.Println(sa.qualifiedName())
+		}
+		return
+	}
+	if config.command == commandRoleUsage {
+		if err := rback.writeRoleUsage(os.Stdout); err != nil {
+			errorf("Can't write the role usage: %v", err)
+			os.Exit(-1)
+		}
+		return
+	}
+
+	if config.groupByLabel != "" && len(rback.permissions.NamespaceLabels) == 0 {
+		warnf("The input doesn't contain any Namespaces, so all namespaces are ungrouped (add namespaces to the kubectl get command)")
+	}
+
+	for _, name := range rback.findMissingResourceNames() {
+		warnf("%s %q not found", config.resourceKind, name)
+	}
+	if config.resourceKind == kindIdentity && !rback.identityBound() {
+		warnf("No bindings grant %s any permissions", config.identity)
+	}
+	for _, binding := range rback.unauthenticatedBindings() {
+		warnf("%s, i.e. to anyone who can reach the API server (this is almost always a mistake)", binding)
+	}
+
+	if config.reportOnly {
+		sections := rback.reportSections()
+		if config.format == formatJSON {
+			if err := writeJSONReport(os.Stdout, sections); err != nil {
+				errorf("Can't write the report: %v", err)
+				os.Exit(-1)
+			}
+		} else {
+			writeTextReport(os.Stdout, sections)
+		}
+		rback.writeStatsIfEnabled()
+		rback.failOnFindings(sections)
+		rback.failOnRisky()
+		return
+	}
+
+	if config.splitBy != "" {
+		if err := rback.writeSplit(config.outputPath); err != nil {
+			errorf("Can't write output split by %s: %v", config.splitBy, err)
+			os.Exit(-1)
+		}
+	} else if config.outputPath != "" {
+		if err := rback.writeFile(config.outputPath); err != nil {
+			errorf("Can't write %s output to %s: %v", config.format, config.outputPath, err)
+			os.Exit(-1)
+		}
+	} else if err := rback.writeMaybeCompressed(os.Stdout); err != nil {
+		errorf("Can't write %s output: %v", config.format, err)
+		os.Exit(-1)
+	}
+
+	if config.splitBy == "" && config.format != formatMetrics && config.format != formatYAML {
+		infof("%s", rback.summary())
+	}
+	rback.writeStatsIfEnabled()
+
+	sections := rback.reportSections()
+	if config.format != formatMarkdown { // the markdown document already contains the findings
+		writeTextReport(os.Stderr, sections)
+	}
+	rback.failOnFindings(sections)
+	rback.failOnRisky()
+
+	if config.failOnEmpty && config.format != formatMetrics && config.splitBy == "" && rback.model.subjectCount() == 0 {
+		errorf("The rendered graph doesn't contain any subjects (check the input and the namespace/resource selection)")
+		os.Exit(-2)
+	}
+}
+
+// validateInputs prints the structural problems of the inputs for the validate command, exiting with a non-zero status
+// if any of them are errors
+func (r *Rback) validateInputs() {
+	problems, err := r.lintInputs()
+	if err != nil {
+		errorf("%v", err)
+		os.Exit(-1)
+	}
+	errors := 0
+	for _, problem := range problems {
+		fmt.Println(problem)
+		if problem.severity == lintError {
+			errors++
+		}
+	}
+	serviceAccounts, roles, bindings := r.permissions.counts()
+	summary := fmt.Sprintf("Found %d errors and %d warnings in %d ServiceAccounts, %d (Cluster)Roles and %d (Cluster)RoleBindings",
+		errors, len(problems)-errors, serviceAccounts, roles, bindings)
+	if errors > 0 {
+		errorf("%s", summary)
+		os.Exit(-3)
+	}
+	infof("%s", summary)
+}
+
+// writeStatsIfEnabled writes the statistics of each namespace to stderr for -stats
+func (r *Rback) writeStatsIfEnabled() {
+	if !r.config.stats {
+		return
+	}
+	if err := r.writeStats(os.Stderr); err != nil {
+		errorf("Can't write the statistics: %v", err)
+		os.Exit(-1)
+	}
+}
+
+// failOnFindings exits with a non-zero status if any of the findings has at least the -fail-on severity
+func (r *Rback) failOnFindings(sections []reportSection) {
+	if r.config.failOn == "" {
+		return
+	}
+	if count := findingsAtOrAbove(sections, r.config.failOn); count > 0 {
+		errorf("Found %d findings of severity %s or higher", count, r.config.failOn)
+		os.Exit(-3)
+	}
+}
+
+// parseInputs parses the RBAC resources from the files given via -f (merging them), or from stdin
+func (r *Rback) parseInputs() error {
+	var err error
+	if r.config.fromBundle != "" {
+		timed("Parsing RBAC resources", func() {
+			err = r.parseBundle(r.config.fromBundle)
+		})
+		return err
+	}
+	if len(r.config.inputFiles) == 0 {
+		debugf("Reading RBAC resources from stdin")
+		timed("Parsing RBAC resources", func() {
+			err = r.parseRBAC(r.keepForBundle("stdin", os.Stdin))
+		})
+		if err != nil {
+			return fmt.Errorf("Can't parse RBAC resources from stdin: %v", err)
+		}
+		r.reportParsed("stdin")
+	}
+	for _, inputFile := range r.config.inputFiles {
+		debugf("Reading RBAC resources from %s", inputFile)
+		reader, err := os.Open(inputFile)
+		if err != nil {
+			return fmt.Errorf("Can't open file %s: %v", inputFile, err)
+		}
+		timed("Parsing RBAC resources", func() {
+			err = r.parseRBAC(r.keepForBundle(inputFile, reader))
+		})
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("Can't parse RBAC resources from %s: %v", inputFile, err)
+		}
+		r.reportParsed(inputFile)
+	}
+	return nil
+}
+
+// expandInputDirs replaces the directories among the paths given via -f with the .json files in them
+func expandInputDirs(paths []string) ([]string, error) {
+	expanded := []string{}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			expanded = append(expanded, path) // opening it will tell what's wrong
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("Directory %s doesn't contain any .json files", path)
+		}
+		expanded = append(expanded, files...)
+	}
+	return expanded, nil
+}
+
+// writeFile writes the output to a temporary file first, which then replaces the given file, so that readers of the
+// file (e.g. a dashboard when using -watch) never see partial output
+func (r *Rback) writeFile(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	err = r.writeMaybeCompressed(tmp)
+	if err == nil {
+		err = tmp.Chmod(0644) // temporary files are only readable by their owner
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// writeMaybeCompressed writes the output (or the image rendered from it) to w, compressed with gzip when using -gzip
+func (r *Rback) writeMaybeCompressed(w io.Writer) error {
+	write := r.writeOutput
+	if r.config.imageFormat != "" {
+		write = r.writeImage
+	}
+	if !r.config.gzip {
+		return write(w)
+	}
+	gz := gzip.NewWriter(w)
+	if err := write(gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// renderGraph generates the graph, unless it has more nodes than allowed by -max-nodes, in which case it would likely be
+// too large to be useful (or to be laid out by Graphviz at all)
+func (r *Rback) renderGraph() (*dot.Graph, error) {
+	var g *dot.Graph
+	timed("Generating graph", func() {
+		g = r.genGraph()
+	})
+	if r.config.maxNodes > 0 && len(r.model.nodes) > r.config.maxNodes {
+		return nil, fmt.Errorf("The graph has %d nodes, more than -max-nodes %d allows; narrow it down with -n, by selecting resources (e.g. rback sa my-sa) or with filters like -subject-kind, -bindings-only or -compact", len(r.model.nodes), r.config.maxNodes)
+	}
+	return g, nil
+}
+
+// writeOutput renders the resources in the configured format
+func (r *Rback) writeOutput(w io.Writer) error {
+	switch r.config.format {
+	case formatMetrics:
+		r.writeMetrics(w)
+	case formatYAML:
+		return r.writeYAML(w)
+	case formatGraphML:
+		if _, err := r.renderGraph(); err != nil {
+			return err
+		}
+		return r.writeGraphML(w)
+	case formatHTML:
+		if _, err := r.renderGraph(); err != nil {
+			return err
+		}
+		return r.writeHTML(w)
+	case formatJSON:
+		if _, err := r.renderGraph(); err != nil {
+			return err
+		}
+		return r.writeJSON(w)
+	case formatMarkdown:
+		g, err := r.renderGraph()
+		if err != nil {
+			return err
+		}
+		return r.writeMarkdown(w, g)
+	default:
+		var output string
+		if len(r.config.contexts) > 0 {
+			var err error
+			if output, err = r.renderContexts(); err != nil {
+				return err
+			}
+		} else {
+			g, err := r.renderGraph()
+			if err != nil {
+				return err
+			}
+			output = g.String()
+		}
+		if r.config.validate {
+			if err := validateDOT(output); err != nil {
+				return fmt.Errorf("Generated invalid DOT: %v", err)
+			}
+		}
+		_, err := fmt.Fprintln(w, output)
+		return err
+	}
+	return nil
+}
+
+// reportParsed reports the number of resources parsed so far (in total, since inputs are merged) for -progress
+func (r *Rback) reportParsed(input string) {
+	serviceAccounts, roles, bindings := r.permissions.counts()
+	r.progressf("Read %s: %d ServiceAccounts, %d (Cluster)Roles and %d (Cluster)RoleBindings so far", input, serviceAccounts, roles, bindings)
+}
+
+// parseConfigFromArgs parses the command line with parseConfig, exiting if it's invalid
+func parseConfigFromArgs() Config {
+	flag.Usage = usage
+	config, err := parseConfig(flag.CommandLine, os.Args[1:], true)
+	if err != nil {
+		errorf("%v", err)
+		os.Exit(-4)
+	}
+	return config
+}
+
+// hiddenFlags are only meant for testing rback itself, so they're left out of the usage
+var hiddenFlags = []string{"validate"}
+
+// usage is like flag.PrintDefaults, except that it skips the hiddenFlags
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	fmt.Fprintf(out, "Flags that aren't passed default to the environment variable %s<FLAG> if set (e.g. %s for -ignore-prefixes)\n", envPrefix, envVarName("ignore-prefixes"))
+	flag.VisitAll(func(f *flag.Flag) {
+		if contains(hiddenFlags, f.Name) {
+			return
+		}
+		name, usage := flag.UnquoteUsage(f)
+		line := "  -" + f.Name
+		if name != "" {
+			line += " " + name
+		}
+		if len(line) <= 4 {
+			line += "\t" // single-letter boolean flags fit on the same line
+		} else {
+			line += "\n    \t"
+		}
+		line += strings.Replace(usage, "\n", "\n    \t", -1)
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "0s" {
+			if name == "string" {
+				line += fmt.Sprintf(" (default %q)", f.DefValue)
+			} else {
+				line += fmt.Sprintf(" (default %v)", f.DefValue)
+			}
+		}
+		fmt.Fprintln(out, line)
+	})
+}
+
+// orphan-sa prints the ServiceAccounts that aren't bound to any role
+const commandOrphanSA = "orphan-sa"
+
+// kubectlVerbs are ignored in front of the positional arguments, so "rback get sa my-sa" works like "rback sa my-sa"
+var kubectlVerbs = []string{"get", "describe"}
+
+// positionalArgs returns the positional arguments left after parsing fs, parsing any flags that follow them as well.
+// Unlike the flag package, kubectl accepts flags after positional arguments (e.g. "sa my-sa -n my-namespace").
+func positionalArgs(fs *flag.FlagSet) ([]string, error) {
+	args := []string{}
+	for fs.NArg() > 0 {
+		rest := fs.Args()
+		args = append(args, rest[0])
+		if err := fs.Parse(rest[1:]); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+const (
+	formatDot      = "dot"
+	formatMetrics  = "metrics"
+	formatGraphML  = "graphml"
+	formatYAML     = "yaml"
+	formatHTML     = "html"
+	formatJSON     = "json" // also a format of the report written by -report-only
+	formatMarkdown = "markdown"
+)
+
+var formats = []string{formatDot, formatMetrics, formatGraphML, formatYAML, formatHTML, formatJSON, formatMarkdown}
+
+// the formats of the report written by -report-only
+const formatText = "text"
+
+var reportFormats = []string{formatText, formatJSON}
+
+const (
+	rulesStyleNote  = "note"
+	rulesStyleTable = "table"
+)
+
+var rulesStyles = []string{rulesStyleNote, rulesStyleTable}
+
+const (
+	rulesFormatFull    = "full"
+	rulesFormatCompact = "compact"
+)
+
+var rulesFormats = []string{rulesFormatFull, rulesFormatCompact}
+
+// what -edge-label shows on the edges from subjects to bindings
+const (
+	edgeLabelBinding = "binding"
+	edgeLabelRole    = "role"
+	edgeLabelKind    = "kind"
+)
+
+var edgeLabels = []string{edgeLabelBinding, edgeLabelRole, edgeLabelKind}
+
+// the values of -orientation
+const (
+	orientationSubjectFirst = "subject-first"
+	orientationRoleFirst    = "role-first"
+)
+
+var orientations = []string{orientationSubjectFirst, orientationRoleFirst}
+
+// splineStyles are the values of the splines graph attribute supported by Graphviz
+var splineStyles = []string{"spline", "ortho", "curved", "polyline", "line", "none"}
+
+const (
+	kindServiceAccount     = "serviceaccount"
+	kindRoleBinding        = "rolebinding"
+	kindClusterRoleBinding = "clusterrolebinding"
+	kindRole               = "role"
+	kindClusterRole        = "clusterrole"
+	kindUser               = "user"
+	kindGroup              = "group"
+	kindRule               = "rule" // internal kind used for nodes that list access rules defined in a role
+)
+
+var kindMap = map[string]string{
+	"sa":                  kindServiceAccount,
+	"serviceaccount":      kindServiceAccount,
+	"serviceaccounts":     kindServiceAccount,
+	"rb":                  kindRoleBinding,
+	"rolebinding":         kindRoleBinding,
+	"rolebindings":        kindRoleBinding,
+	"crb":                 kindClusterRoleBinding,
+	"clusterrolebinding":  kindClusterRoleBinding,
+	"clusterrolebindings": kindClusterRoleBinding,
+	"r":                   kindRole,
+	"role":                kindRole,
+	"roles":               kindRole,
+	"cr":                  kindClusterRole,
+	"clusterrole":         kindClusterRole,
+	"clusterroles":        kindClusterRole,
+	"u":                   kindUser,
+	"user":                kindUser,
+	"users":               kindUser,
+	"g":                   kindGroup,
+	"group":               kindGroup,
+	"groups":              kindGroup,
+}
+
+// rbacAPIGroup is stripped from fully-qualified kinds (e.g. "roles.rbac.authorization.k8s.io"), as accepted by kubectl
+const rbacAPIGroup = ".rbac.authorization.k8s.io"
+
+func normalizeKind(kind string) string {
+	kind = strings.ToLower(kind)
+	kind = strings.TrimSuffix(kind, rbacAPIGroup)
+	entry, exists := kindMap[kind]
+	if exists {
+		return entry
+	}
+	return kind
+}
@@ -0,0 +1,88 @@
+package rback
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testConfig parses the given command line arguments like main does, so that tests get the same defaults
+func testConfig(t testing.TB, args ...string) Config {
+	t.Helper()
+	config, err := parseConfig(flag.NewFlagSet("rback", flag.ContinueOnError), args, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setVerbosity(config.verbose, config.quiet)
+	return config
+}
+
+// testRback parses the given input files (relative to the package directory) with the given config
+func testRback(t *testing.T, config Config, inputFiles ...string) *Rback {
+	t.Helper()
+	r := &Rback{config: config}
+	for _, inputFile := range inputFiles {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.parseRBAC(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", inputFile, err)
+		}
+	}
+	return r
+}
+
+// testRbackFromItems parses a List of the given items (each a JSON object) with the given config
+func testRbackFromItems(t *testing.T, config Config, items ...string) *Rback {
+	t.Helper()
+	r := &Rback{config: config}
+	list := `{"apiVersion": "v1", "kind": "List", "items": [` + strings.Join(items, ",") + `]}`
+	if err := r.parseRBAC(strings.NewReader(list)); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestNormalizeKind(t *testing.T) {
+	tests := []struct {
+		kind     string
+		expected string
+	}{
+		{"sa", kindServiceAccount},
+		{"serviceaccount", kindServiceAccount},
+		{"serviceaccounts", kindServiceAccount},
+		{"ServiceAccount", kindServiceAccount},
+		{"rb", kindRoleBinding},
+		{"rolebinding", kindRoleBinding},
+		{"rolebindings", kindRoleBinding},
+		{"rolebindings.rbac.authorization.k8s.io", kindRoleBinding},
+		{"crb", kindClusterRoleBinding},
+		{"clusterrolebinding", kindClusterRoleBinding},
+		{"clusterrolebindings", kindClusterRoleBinding},
+		{"ClusterRoleBinding.rbac.authorization.k8s.io", kindClusterRoleBinding},
+		{"r", kindRole},
+		{"role", kindRole},
+		{"roles", kindRole},
+		{"roles.rbac.authorization.k8s.io", kindRole},
+		{"cr", kindClusterRole},
+		{"clusterrole", kindClusterRole},
+		{"clusterroles", kindClusterRole},
+		{"clusterroles.rbac.authorization.k8s.io", kindClusterRole},
+		{"u", kindUser},
+		{"user", kindUser},
+		{"users", kindUser},
+		{"g", kindGroup},
+		{"group", kindGroup},
+		{"groups", kindGroup},
+		{"pods", "pods"}, // unknown kinds are passed through
+	}
+	for _, test := range tests {
+		if actual := normalizeKind(test.kind); actual != test.expected {
+			t.Errorf("normalizeKind(%q) = %q, expected %q", test.kind, actual, test.expected)
+		}
+	}
+}
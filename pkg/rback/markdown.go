@@ -0,0 +1,80 @@
+package rback
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/dot"
+)
+
+// the sections of -format markdown, which can be selected via -markdown-sections
+const (
+	markdownSubjects = "subjects"
+	markdownFindings = "findings"
+	markdownGraph    = "graph"
+)
+
+var markdownSections = []string{markdownSubjects, markdownFindings, markdownGraph}
+
+// writeMarkdown writes a report for reading in pull requests and the like: a table of the rendered subjects and the
+// roles bound to them, the findings of the analysis passes, and the graph itself as a dot code block
+func (r *Rback) writeMarkdown(w io.Writer, g *dot.Graph) error {
+	fmt.Fprintf(w, "# %s\n", iff(r.config.title != "", r.config.title, "RBAC report"))
+	if contains(r.config.markdownSections, markdownSubjects) {
+		fmt.Fprintf(w, "\n## Subjects\n\n| Subject | Kind | Namespace | Roles |\n| --- | --- | --- | --- |\n")
+		for _, row := range r.subjectRoles() {
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", escapeMarkdown(row.subject.name), row.subject.kind,
+				escapeMarkdown(row.subject.namespace), escapeMarkdown(strings.Join(row.roles, ", ")))
+		}
+	}
+	if contains(r.config.markdownSections, markdownFindings) {
+		fmt.Fprintf(w, "\n## Findings\n")
+		for _, section := range r.reportSections() {
+			fmt.Fprintf(w, "\n### %s (%s, %s): %d found\n\n", section.Title, section.Code, section.Severity, len(section.Findings))
+			for _, f := range section.Findings {
+				fmt.Fprintf(w, "* %s\n", escapeMarkdown(f))
+			}
+		}
+	}
+	if contains(r.config.markdownSections, markdownGraph) {
+		fmt.Fprintf(w, "\n## Graph\n\n```dot\n%s\n```\n", g.String())
+	}
+	return nil
+}
+
+type subjectRolesRow struct {
+	subject KindNamespacedName
+	roles   []string // e.g. "ClusterRole view (RoleBinding a/view)"
+}
+
+// subjectRoles returns the roles bound to each rendered subject by the rendered bindings, sorted by kind and name
+func (r *Rback) subjectRoles() []subjectRolesRow {
+	roles := map[KindNamespacedName][]string{}
+	for _, bindings := range r.permissions.RoleBindings {
+		for _, binding := range bindings {
+			if !r.model.nodeIDs[r.bindingNodeID(binding)] {
+				continue
+			}
+			f := finding{binding: binding.NamespacedName, role: binding.role}
+			for _, subject := range binding.subjects {
+				if r.model.nodeIDs[subjectNodeID(subject.kind, subject.namespace, subject.name)] {
+					roles[subject] = append(roles[subject], fmt.Sprintf("%s (%s)", f.roleDescription(), f.bindingDescription()))
+				}
+			}
+		}
+	}
+	rows := []subjectRolesRow{}
+	for subject, subjectRoles := range roles {
+		sort.Strings(subjectRoles)
+		rows = append(rows, subjectRolesRow{subject, subjectRoles})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].subject.kind+rows[i].subject.qualifiedName() < rows[j].subject.kind+rows[j].subject.qualifiedName()
+	})
+	return rows
+}
+
+// escapeMarkdown escapes the characters that would break table cells or be taken as formatting
+var escapeMarkdown = strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`").Replace
@@ -0,0 +1,96 @@
+package rback
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// writeMetrics writes statistics about the parsed RBAC resources in the Prometheus text exposition format
+func (r *Rback) writeMetrics(w io.Writer) {
+	serviceAccounts := map[string]int{}
+	for ns, sas := range r.permissions.ServiceAccounts {
+		if r.namespaceSelected(ns) {
+			serviceAccounts[ns] = len(sas)
+		}
+	}
+
+	roles := map[string]int{}
+	wildcardRoles := map[string]int{}
+	clusterRoles, wildcardClusterRoles := 0, 0
+	for ns, nsRoles := range r.permissions.Roles {
+		for _, role := range nsRoles {
+			if ns == "" {
+				clusterRoles++
+				if role.grantsWildcard() {
+					wildcardClusterRoles++
+				}
+			} else if r.namespaceSelected(ns) {
+				roles[ns]++
+				if role.grantsWildcard() {
+					wildcardRoles[ns]++
+				}
+			}
+		}
+	}
+
+	roleBindings := map[string]int{}
+	clusterRoleBindings := 0
+	subjects := map[string]map[KindNamespacedName]bool{} // map[kind]set of subjects
+	for ns, bindings := range r.permissions.RoleBindings {
+		if ns == "" {
+			clusterRoleBindings = len(bindings)
+		} else if r.namespaceSelected(ns) {
+			roleBindings[ns] = len(bindings)
+		} else {
+			continue
+		}
+		for _, binding := range bindings {
+			for _, subject := range binding.subjects {
+				if subjects[subject.kind] == nil {
+					subjects[subject.kind] = make(map[KindNamespacedName]bool)
+				}
+				subjects[subject.kind][subject] = true
+			}
+		}
+	}
+	distinctSubjects := map[string]int{}
+	for kind, set := range subjects {
+		distinctSubjects[kind] = len(set)
+	}
+
+	writeNamespacedMetric(w, "rback_service_accounts", "Number of ServiceAccounts.", serviceAccounts)
+	writeNamespacedMetric(w, "rback_roles", "Number of Roles.", roles)
+	writeNamespacedMetric(w, "rback_roles_granting_wildcard", "Number of Roles granting all verbs or all resources.", wildcardRoles)
+	writeMetric(w, "rback_cluster_roles", "Number of ClusterRoles.", clusterRoles)
+	writeMetric(w, "rback_cluster_roles_granting_wildcard", "Number of ClusterRoles granting all verbs or all resources.", wildcardClusterRoles)
+	writeNamespacedMetric(w, "rback_role_bindings", "Number of RoleBindings.", roleBindings)
+	writeMetric(w, "rback_cluster_role_bindings", "Number of ClusterRoleBindings.", clusterRoleBindings)
+	writeLabeledMetric(w, "rback_distinct_subjects", "Number of distinct subjects referenced by bindings.", "kind", distinctSubjects)
+}
+
+func writeMetric(w io.Writer, name, help string, value int) {
+	writeMetricHeader(w, name, help)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+func writeNamespacedMetric(w io.Writer, name, help string, values map[string]int) {
+	writeLabeledMetric(w, name, help, "namespace", values)
+}
+
+func writeLabeledMetric(w io.Writer, name, help, label string, values map[string]int) {
+	writeMetricHeader(w, name, help)
+	labelValues := []string{}
+	for labelValue := range values {
+		labelValues = append(labelValues, labelValue)
+	}
+	sort.Strings(labelValues)
+	for _, labelValue := range labelValues {
+		fmt.Fprintf(w, "%s{%s=%q} %d\n", name, label, labelValue, values[labelValue])
+	}
+}
+
+func writeMetricHeader(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+}
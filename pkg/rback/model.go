@@ -0,0 +1,56 @@
+package rback
+
+// graphModel records the nodes and edges that genGraph adds to the dot graph (excluding the legend), so that the same
+// graph can also be exported in formats other than dot
+type graphModel struct {
+	nodes   []modelNode
+	edges   []modelEdge
+	nodeIDs map[string]bool
+	edgeIDs map[modelEdge]bool
+}
+
+type modelNode struct {
+	id        string
+	kind      string // e.g. ServiceAccount, RoleBinding, ClusterRole or Rules
+	namespace string
+	label     string
+}
+
+type modelEdge struct {
+	source, target string
+}
+
+func newGraphModel() graphModel {
+	return graphModel{
+		nodeIDs: make(map[string]bool),
+		edgeIDs: make(map[modelEdge]bool),
+	}
+}
+
+func (m *graphModel) addNode(id, kind, namespace, label string) {
+	if m.nodeIDs[id] {
+		return
+	}
+	m.nodeIDs[id] = true
+	m.nodes = append(m.nodes, modelNode{id: id, kind: kind, namespace: namespace, label: label})
+}
+
+func (m *graphModel) addEdge(source, target string) {
+	e := modelEdge{source, target}
+	if m.edgeIDs[e] {
+		return
+	}
+	m.edgeIDs[e] = true
+	m.edges = append(m.edges, e)
+}
+
+// subjectCount returns the number of ServiceAccount, User and Group nodes
+func (m *graphModel) subjectCount() int {
+	count := 0
+	for _, n := range m.nodes {
+		if n.kind == "ServiceAccount" || n.kind == "User" || n.kind == "Group" {
+			count++
+		}
+	}
+	return count
+}
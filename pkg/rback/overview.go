@@ -0,0 +1,37 @@
+package rback
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emicklei/dot"
+)
+
+// renderOverview renders a row of nodes at the top of the graph, one per rendered namespace, each connected by a faint
+// edge to the cluster of its namespace. In SVG output, clicking one of them jumps to the namespace.
+func (r *Rback) renderOverview(g *dot.Graph) {
+	if !r.config.overview || len(r.namespaceGraphs) == 0 {
+		return
+	}
+	namespaces := []string{}
+	for ns := range r.namespaceGraphs {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	g.Attr("compound", "true") // for edges ending at the border of a cluster (lhead)
+	// a (non-cluster) subgraph puts the nodes into one row, like AddToSameRank would, but the library writes the groups
+	// of AddToSameRank (like that of the roles) in random order; its title can't be a namespace, as it contains a space
+	row := g.Subgraph("overview row")
+	row.Attr("rank", "same")
+	for i, ns := range namespaces {
+		gns := r.namespaceGraphs[ns]
+		// the generated IDs of clusters aren't exposed, so the edge needs one that's known (and a valid DOT ID)
+		clusterID := fmt.Sprintf("cluster_namespace%d", i)
+		gns.ID(clusterID)
+		gns.Attr("id", "namespace-"+ns) // the ID of the cluster in SVG output
+		anchor := newOverviewAnchorNode(gns, ns)
+		node := newOverviewNode(row, ns).Attr("URL", "#namespace-"+ns)
+		edge(node, anchor).Attr("lhead", clusterID).Attr("style", "dashed").Attr("color", "#c8c8c8").Attr("arrowhead", "none")
+	}
+}
@@ -0,0 +1,363 @@
+package rback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// kubeList, kubeObject and friends mirror the parts of the Kubernetes API types that rback uses, so that each input is
+// decoded only once into typed values (instead of asserting the types of generic maps all over the place)
+type kubeList struct {
+	Kind  string            `json:"kind"`
+	Items []json.RawMessage `json:"items"`
+}
+
+type kubeObject struct {
+	Kind     string       `json:"kind"`
+	Metadata kubeMetadata `json:"metadata"`
+
+	// ServiceAccount
+	AutomountServiceAccountToken *bool           `json:"automountServiceAccountToken"`
+	Secrets                      []kubeObjectRef `json:"secrets"`
+	ImagePullSecrets             []kubeObjectRef `json:"imagePullSecrets"`
+
+	// Role, ClusterRole
+	Rules []kubeRule `json:"rules"`
+
+	// RoleBinding, ClusterRoleBinding
+	RoleRef  kubeRoleRef   `json:"roleRef"`
+	Subjects []kubeSubject `json:"subjects"`
+}
+
+type kubeMetadata struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	CreationTimestamp string            `json:"creationTimestamp"`
+	Labels            map[string]string `json:"labels"`
+	Annotations       map[string]string `json:"annotations"`
+}
+
+// created returns the creation time of the resource, or the zero time if it's unknown
+func (m kubeMetadata) created() time.Time {
+	created, _ := time.Parse(time.RFC3339, m.CreationTimestamp)
+	return created
+}
+
+// the annotations that override the colors of an object's node
+const (
+	colorAnnotation     = "rback.io/color"
+	fontColorAnnotation = "rback.io/font-color"
+)
+
+// graphvizColor matches the colors Graphviz accepts as attribute values: #RRGGBB (optionally with alpha) or a name
+var graphvizColor = regexp.MustCompile(`^(#[0-9a-fA-F]{6}([0-9a-fA-F]{2})?|[a-zA-Z]+[0-9]*)$`)
+
+// colors returns the node colors set through the rback.io/color and rback.io/font-color annotations, ignoring (with a
+// warning) those that aren't colors
+func (m kubeMetadata) colors() nodeColors {
+	colors := nodeColors{}
+	for _, a := range []struct {
+		annotation string
+		color      *string
+	}{{colorAnnotation, &colors.fill}, {fontColorAnnotation, &colors.font}} {
+		annotation, color := a.annotation, a.color
+		value, ok := m.Annotations[annotation]
+		if !ok {
+			continue
+		}
+		if !graphvizColor.MatchString(value) {
+			warnf("Ignoring annotation %s of %s: %q isn't a color (e.g. #ff0000 or red)", annotation,
+				NamespacedName{m.Namespace, m.Name}.qualifiedName(), value)
+			continue
+		}
+		*color = value
+	}
+	return colors
+}
+
+type kubeRule struct {
+	Verbs           []string `json:"verbs"`
+	APIGroups       []string `json:"apiGroups"`
+	Resources       []string `json:"resources"`
+	ResourceNames   []string `json:"resourceNames"`
+	NonResourceURLs []string `json:"nonResourceURLs"`
+}
+
+// kubeObjectRef is a reference to another object in the same namespace, e.g. a Secret of a ServiceAccount
+type kubeObjectRef struct {
+	Name string `json:"name"`
+}
+
+func objectRefNames(refs []kubeObjectRef) []string {
+	names := []string{}
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	return names
+}
+
+type kubeRoleRef struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"` // not part of the RBAC API, but set by some extensions (or bugs)
+}
+
+type kubeSubject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// parseRBAC parses RBAC resources from the given reader and stores them in maps under r.permissions
+func (r *Rback) parseRBAC(reader io.Reader) (err error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	items, itemKind, err := kubeItems(data)
+	if err != nil {
+		return err
+	}
+
+	// parseRBAC may be called once per input, in which case the results are merged
+	if r.permissions.ServiceAccounts == nil {
+		r.permissions.ServiceAccounts = make(map[string]map[string]ServiceAccount)
+		r.permissions.Roles = make(map[string]map[string]Role)
+		r.permissions.RoleBindings = make(map[string]map[string]Binding)
+		r.permissions.IgnoredRoleBindings = make(map[string]map[string]Binding)
+		r.permissions.IgnoredRoles = make(map[string]map[string]bool)
+		r.permissions.NamespaceLabels = make(map[string]map[string]string)
+	}
+
+	r.resetEffectiveRules()
+
+	// kubectl returns "items": [] (or even null) for namespaces without any of the requested resources, which is fine
+	if len(items) == 0 {
+		debugf("Input contains no items")
+	}
+
+	for i, rawItem := range items {
+		var item kubeObject
+		if err := json.Unmarshal(rawItem, &item); err != nil {
+			return fmt.Errorf("Item %d is not a valid resource (%v): %q", i, err, excerpt(rawItem, 200))
+		}
+		if item.Kind == "" {
+			item.Kind = itemKind
+		}
+		nn := NamespacedName{item.Metadata.Namespace, item.Metadata.Name}
+
+		if r.namespaceExcluded(nn.namespace) {
+			continue
+		}
+
+		if r.shouldIgnore(nn.name) {
+			if item.Kind == "RoleBinding" || item.Kind == "ClusterRoleBinding" {
+				if r.permissions.IgnoredRoleBindings[nn.namespace] == nil {
+					r.permissions.IgnoredRoleBindings[nn.namespace] = make(map[string]Binding)
+				}
+				r.permissions.IgnoredRoleBindings[nn.namespace][nn.name] = r.toBinding(item)
+			}
+			if item.Kind == "Role" || item.Kind == "ClusterRole" {
+				if r.permissions.IgnoredRoles[nn.namespace] == nil {
+					r.permissions.IgnoredRoles[nn.namespace] = make(map[string]bool)
+				}
+				r.permissions.IgnoredRoles[nn.namespace][nn.name] = true
+			}
+			continue
+		}
+
+		// the resources as read are only needed for -format yaml, and decoding them generically takes as long as the
+		// rest of the parsing, so it's skipped otherwise
+		switch item.Kind {
+		case "ServiceAccount", "RoleBinding", "ClusterRoleBinding", "Role", "ClusterRole", "Namespace":
+			if r.config.format != formatYAML {
+				break
+			}
+			var object map[string]interface{}
+			if err := json.Unmarshal(rawItem, &object); err != nil {
+				return err
+			}
+			object["kind"] = item.Kind
+			r.permissions.Objects = append(r.permissions.Objects, object)
+		}
+
+		switch item.Kind {
+		case "ServiceAccount":
+			if r.permissions.ServiceAccounts[nn.namespace] == nil {
+				r.permissions.ServiceAccounts[nn.namespace] = make(map[string]ServiceAccount)
+			}
+			r.permissions.ServiceAccounts[nn.namespace][nn.name] = toServiceAccount(item, rawItem)
+		case "RoleBinding", "ClusterRoleBinding":
+			if r.permissions.RoleBindings[nn.namespace] == nil {
+				r.permissions.RoleBindings[nn.namespace] = make(map[string]Binding)
+			}
+			r.permissions.RoleBindings[nn.namespace][nn.name] = r.toBinding(item)
+		case "Role", "ClusterRole":
+			if r.permissions.Roles[nn.namespace] == nil {
+				r.permissions.Roles[nn.namespace] = make(map[string]Role)
+			}
+			r.permissions.Roles[nn.namespace][nn.name] = toRole(item)
+		case "Namespace":
+			r.permissions.NamespaceLabels[nn.name] = item.Metadata.Labels
+		default:
+			debugf("Ignoring resource kind %s", item.Kind)
+		}
+	}
+	return nil
+}
+
+// kubeItems returns the items of the List (or the single object) in data, along with the kind of items that don't have
+// a kind field of their own
+func kubeItems(data []byte) (items []json.RawMessage, itemKind string, err error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, "", fmt.Errorf("No input received (did the kubectl command producing it fail?)")
+	}
+	var input kubeList
+	if err := json.Unmarshal(data, &input); err != nil {
+		if !looksLikeYAML(data) {
+			return nil, "", fmt.Errorf("Input is not valid JSON (%v); it starts with: %q", err, excerpt(data, 200))
+		}
+		converted, err := yamlToJSON(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("Input is not valid YAML (%v); it starts with: %q", err, excerpt(data, 200))
+		}
+		return kubeItems(converted)
+	}
+
+	// a List can contain resources of mixed kinds (e.g. from kubectl get sa,roles,rolebindings), so each item is
+	// routed by its own kind
+	switch input.Kind {
+	case "List":
+	case "ServiceAccountList", "RoleBindingList", "ClusterRoleBindingList", "RoleList", "ClusterRoleList", "NamespaceList":
+		// the API itself (e.g. kubectl get --raw) returns lists of a single kind, whose items don't have a kind field
+		itemKind = strings.TrimSuffix(input.Kind, "List")
+	case "ServiceAccount", "RoleBinding", "ClusterRoleBinding", "Role", "ClusterRole", "Namespace":
+		// kubectl returns a single object instead of a List when getting exactly one resource by name
+		input.Items = []json.RawMessage{data}
+	default:
+		return nil, "", fmt.Errorf("Expected kind=List, but found %q", input.Kind)
+	}
+	return input.Items, itemKind, nil
+}
+
+// namespaceExcluded returns true for namespaces excluded via -exclude-namespaces (cluster-scoped resources are never excluded)
+func (r *Rback) namespaceExcluded(ns string) bool {
+	return ns != "" && contains(r.config.excludedNamespaces, ns)
+}
+
+func (r *Rback) shouldIgnore(name string) bool {
+	for _, prefix := range r.config.ignoredPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func toServiceAccount(item kubeObject, rawItem json.RawMessage) ServiceAccount {
+	var compacted bytes.Buffer
+	json.Compact(&compacted, rawItem)
+	return ServiceAccount{
+		NamespacedName: NamespacedName{item.Metadata.Namespace, item.Metadata.Name},
+		json:           compacted.String(),
+		// tokens are automounted by default
+		automountToken:   item.AutomountServiceAccountToken == nil || *item.AutomountServiceAccountToken,
+		secrets:          objectRefNames(item.Secrets),
+		imagePullSecrets: objectRefNames(item.ImagePullSecrets),
+		colors:           item.Metadata.colors(),
+		annotations:      item.Metadata.Annotations,
+	}
+}
+
+func toRole(item kubeObject) Role {
+	rules := []Rule{}
+	for _, rule := range item.Rules {
+		rules = append(rules, toRule(rule))
+	}
+
+	return Role{
+		NamespacedName{item.Metadata.Namespace, item.Metadata.Name},
+		rules,
+		item.Metadata.created(),
+		item.Metadata.colors(),
+		item.Metadata.Annotations,
+	}
+}
+
+func (r *Rback) toBinding(item kubeObject) Binding {
+	subjects := []KindNamespacedName{}
+	for _, s := range item.Subjects {
+		subject := KindNamespacedName{s.Kind, NamespacedName{s.Namespace, s.Name}}
+		// the virtual ServiceAccount groups match the default ignored prefix "system:", but binding them is a commonly
+		// missed way of granting permissions to many ServiceAccounts at once, so they're kept
+		groupNs, isServiceAccountsGroup := serviceAccountsGroupNamespace(subject)
+		if isServiceAccountsGroup && !r.namespaceExcluded(groupNs) {
+			subjects = append(subjects, subject)
+		} else if publicSubject(subject) {
+			subjects = append(subjects, subject) // they match the ignored prefix "system:" too, but are security-critical
+		} else if r.config.resourceKind == kindIdentity && r.config.identity.includes(subject) {
+			subjects = append(subjects, subject) // e.g. system:authenticated, which grants the identity permissions as well
+		} else if !r.shouldIgnore(subject.name) && !r.namespaceExcluded(subject.namespace) {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	bindingNn := NamespacedName{item.Metadata.Namespace, item.Metadata.Name}
+
+	// roleRef has no namespace field: the scope is determined by its kind alone. A Role is looked up in the binding's
+	// namespace, while ClusterRoles are stored under the "" namespace. Some RBAC extensions (or bugs) still produce
+	// roleRefs with a namespace, which is then used for Roles, as that's where the role is meant to be found.
+	role := NamespacedName{name: item.RoleRef.Name}
+	switch kind := item.RoleRef.Kind; kind {
+	case "Role":
+		role.namespace = bindingNn.namespace
+		if ns := item.RoleRef.Namespace; ns != "" && ns != bindingNn.namespace {
+			warnf("Binding %s/%s references Role %s in another namespace (%s), which Kubernetes doesn't support", bindingNn.namespace, bindingNn.name, role.name, ns)
+			role.namespace = ns
+		}
+	case "ClusterRole":
+		role.namespace = ""
+	default:
+		warnf("Binding %s/%s references role %s of unknown kind %q, treating it as a ClusterRole", bindingNn.namespace, bindingNn.name, role.name, kind)
+	}
+	return Binding{
+		NamespacedName: bindingNn,
+		role:           role,
+		subjects:       subjects,
+		created:        item.Metadata.created(),
+		colors:         item.Metadata.colors(),
+		annotations:    item.Metadata.Annotations,
+	}
+}
+
+func toRule(rule kubeRule) Rule {
+	return Rule{
+		verbs:           nonNil(rule.Verbs),
+		resources:       nonNil(rule.Resources),
+		resourceNames:   nonNil(rule.ResourceNames),
+		nonResourceURLs: nonNil(rule.NonResourceURLs),
+		apiGroups:       nonNil(rule.APIGroups),
+	}
+}
+
+// nonNil turns missing lists into empty ones, which is how they're rendered
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// excerpt returns at most the first n bytes of data, for use in error messages
+func excerpt(data []byte, n int) string {
+	if len(data) > n {
+		return string(data[:n]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,115 @@
+package rback
+
+import (
+	"bytes"
+	"os/exec"
+	"sort"
+	"sync"
+	"testing"
+)
+
+var (
+	largeInputOnce sync.Once
+	largeInput     []byte
+	largeInputErr  error
+)
+
+// benchmarkInput returns the input of a large cluster with 5000 RoleBindings, as generated for make bench
+func benchmarkInput(b *testing.B) []byte {
+	b.Helper()
+	largeInputOnce.Do(func() {
+		largeInput, largeInputErr = exec.Command("../../examples/generate-large-rbac.sh", "200").Output()
+	})
+	if largeInputErr != nil {
+		b.Fatalf("Can't generate the input: %v", largeInputErr)
+	}
+	return largeInput
+}
+
+func BenchmarkParseRBAC(b *testing.B) {
+	input := benchmarkInput(b)
+	config := testConfig(b, "-quiet")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := Rback{config: config}
+		if err := r.parseRBAC(bytes.NewReader(input)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestExcludedNamespacesOverlappingSelectedNamespaces(t *testing.T) {
+	items := []string{
+		`{"kind": "ServiceAccount", "metadata": {"name": "app", "namespace": "a"}}`,
+		`{"kind": "ServiceAccount", "metadata": {"name": "app", "namespace": "b"}}`,
+		`{"kind": "ServiceAccount", "metadata": {"name": "app", "namespace": "c"}}`,
+		`{"kind": "ClusterRole", "metadata": {"name": "view"},
+		  "rules": [{"apiGroups": [""], "resources": ["pods"], "verbs": ["get"]}]}`,
+		`{"kind": "RoleBinding", "metadata": {"name": "view", "namespace": "a"}, "roleRef": {"kind": "ClusterRole", "name": "view"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "app", "namespace": "a"}]}`,
+		`{"kind": "RoleBinding", "metadata": {"name": "view", "namespace": "b"}, "roleRef": {"kind": "ClusterRole", "name": "view"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "app", "namespace": "b"}]}`,
+		`{"kind": "ClusterRoleBinding", "metadata": {"name": "view-all"}, "roleRef": {"kind": "ClusterRole", "name": "view"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "app", "namespace": "b"},
+		               {"kind": "ServiceAccount", "name": "app", "namespace": "c"}]}`,
+	}
+	tests := []struct {
+		args     []string
+		expected []string // the rendered ServiceAccounts and bindings
+	}{
+		{[]string{"-exclude-namespaces", "b"},
+			[]string{"ClusterRoleBinding view-all", "RoleBinding a/view", "ServiceAccount a/app", "ServiceAccount c/app"}},
+		// exclusions apply within the selected namespaces
+		{[]string{"-n", "a,b", "-exclude-namespaces", "b"},
+			[]string{"RoleBinding a/view", "ServiceAccount a/app"}},
+		// excluding a namespace that isn't selected changes nothing
+		{[]string{"-n", "a", "-exclude-namespaces", "c"},
+			[]string{"RoleBinding a/view", "ServiceAccount a/app"}},
+		// excluding all the selected namespaces leaves nothing, not everything
+		{[]string{"-n", "a,b", "-exclude-namespaces", "a,b"},
+			[]string{}},
+		// the ClusterRoleBinding is cluster-scoped, so it's never excluded, only its subjects in excluded namespaces
+		{[]string{"-n", "c", "-exclude-namespaces", "b"},
+			[]string{"ClusterRoleBinding view-all", "ServiceAccount c/app"}},
+		{[]string{"-n", "b", "-exclude-namespaces", "b,c"},
+			[]string{}},
+	}
+	for _, test := range tests {
+		r := testRbackFromItems(t, testConfig(t, append([]string{"-quiet", "-show-legend=false"}, test.args...)...), items...)
+		r.genGraph()
+		actual := []string{}
+		for _, node := range r.model.nodes {
+			switch node.kind {
+			case "ServiceAccount", "RoleBinding", "ClusterRoleBinding":
+				actual = append(actual, node.kind+" "+NamespacedName{node.namespace, node.label}.qualifiedName())
+			}
+		}
+		sort.Strings(actual)
+		if !equalStrings(actual, test.expected) {
+			t.Errorf("%v: rendered %q, expected %q", test.args, actual, test.expected)
+		}
+	}
+}
+
+func TestRoleRefScopeFromKind(t *testing.T) {
+	r := testRback(t, testConfig(t, "-quiet"), "../../examples/roleref-kinds.json")
+	tests := []struct {
+		binding string
+		role    NamespacedName
+		rules   string
+	}{
+		// a Role is looked up in the namespace of the binding, even though the roleRef has no namespace
+		{"to-role", NamespacedName{"app", "view"}, "get configmaps"},
+		{"to-clusterrole", NamespacedName{"", "view"}, "get,list pods"},
+	}
+	for _, test := range tests {
+		binding := r.permissions.RoleBindings["app"][test.binding]
+		if binding.role != test.role {
+			t.Errorf("RoleBinding app/%s references %v, expected %v", test.binding, binding.role, test.role)
+		}
+		role, found := r.lookupRole(binding.role)
+		if !found || len(role.rules) != 1 || role.rules[0].toHumanReadableString() != test.rules {
+			t.Errorf("RoleBinding app/%s grants %v, expected the rule %s", test.binding, role.rules, test.rules)
+		}
+	}
+}
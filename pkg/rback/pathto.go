@@ -0,0 +1,227 @@
+package rback
+
+import (
+	"sort"
+	"strings"
+)
+
+// the targets of -path-to
+const pathToClusterAdmin = "cluster-admin"
+
+var pathTargets = []string{pathToClusterAdmin}
+
+// systemMasters is the group that Kubernetes grants cluster-admin access to, regardless of any bindings
+const systemMasters = "system:masters"
+
+// grantsEverything returns true if the rule grants all verbs on all resources in all API groups, like cluster-admin does
+func (rule *Rule) grantsEverything() bool {
+	return contains(rule.verbs, "*") && contains(rule.resources, "*") && contains(rule.apiGroups, "*")
+}
+
+// escalationRoutes are the pairs of permissions that, granted cluster-wide, allow a subject to give itself cluster-admin
+// access: binding cluster-admin by creating a ClusterRoleBinding, or adding everything to a ClusterRole (bound to it)
+var escalationRoutes = [][2]WhoCan{
+	{
+		{verb: "create", resourceKind: "clusterrolebindings", apiGroup: "rbac.authorization.k8s.io"},
+		{verb: "bind", resourceKind: "clusterroles", apiGroup: "rbac.authorization.k8s.io", resourceName: pathToClusterAdmin},
+	},
+	{
+		{verb: "update", resourceKind: "clusterroles", apiGroup: "rbac.authorization.k8s.io"},
+		{verb: "escalate", resourceKind: "clusterroles", apiGroup: "rbac.authorization.k8s.io"},
+	},
+}
+
+// adminPath is a chain of grants that leads a subject to cluster-admin access. Each grant of impersonation hands over
+// to the impersonated subject, whose grants follow it.
+type adminPath []finding
+
+func (p adminPath) String() string {
+	steps := []string{}
+	for _, f := range p {
+		steps = append(steps, f.String())
+	}
+	return strings.Join(steps, ", then ")
+}
+
+// findAdminPaths returns the shortest chain of grants that gives each subject cluster-admin access, either directly, by
+// writing RBAC resources cluster-wide, or by impersonating a subject that has such a chain (or the system:masters group).
+// Only grants of the selected namespaces are considered (see findGrants).
+func (r *Rback) findAdminPaths() map[KindNamespacedName]adminPath {
+	grants := r.findGrants(func(rule Rule) bool { return true })
+	subjectGrants := map[KindNamespacedName][]finding{}
+	for _, f := range grants {
+		subjectGrants[f.subject] = append(subjectGrants[f.subject], f)
+	}
+
+	paths := map[KindNamespacedName]adminPath{}
+	for subject, grants := range subjectGrants {
+		if path, ok := directAdminPath(grants); ok {
+			paths[subject] = path
+		}
+	}
+
+	// each round finds the subjects that can impersonate one with a path of the previous rounds, so the paths are shortest
+	for {
+		found := map[KindNamespacedName]adminPath{}
+		for _, f := range grants {
+			if _, ok := paths[f.subject]; ok {
+				continue
+			}
+			if _, ok := found[f.subject]; ok {
+				continue
+			}
+			if target, ok := r.impersonatedAdmin(f, paths); ok {
+				found[f.subject] = append(adminPath{f}, paths[target]...)
+			} else if f.binding.namespace == "" && impersonatesSystemMasters(f.rule) {
+				found[f.subject] = adminPath{f}
+			}
+		}
+		if len(found) == 0 {
+			return paths
+		}
+		for subject, path := range found {
+			paths[subject] = path
+		}
+	}
+}
+
+// directAdminPath returns the grants that give cluster-admin access without impersonation, if any
+func directAdminPath(grants []finding) (adminPath, bool) {
+	for _, f := range grants {
+		if f.binding.namespace == "" && f.rule.grantsEverything() {
+			return adminPath{f}, true
+		}
+	}
+	for _, route := range escalationRoutes {
+		path := adminPath{}
+		for _, permission := range route {
+			for _, f := range grants {
+				if f.binding.namespace == "" && permission.matches(f.rule) {
+					path = append(path, f)
+					break
+				}
+			}
+		}
+		if len(path) == len(route) {
+			if path[0].String() == path[1].String() {
+				path = path[:1] // a single rule grants both
+			}
+			return path, true
+		}
+	}
+	return nil, false
+}
+
+// impersonatesSystemMasters returns true if the rule allows impersonating the system:masters group
+func impersonatesSystemMasters(rule Rule) bool {
+	return contains(rule.impersonatedKinds(), "Group") &&
+		(len(rule.resourceNames) == 0 || contains(rule.resourceNames, systemMasters))
+}
+
+// impersonatedAdmin returns a subject with a path to cluster-admin that the grant allows impersonating, if any.
+// ServiceAccounts can only be impersonated in the namespace of a RoleBinding.
+func (r *Rback) impersonatedAdmin(f finding, paths map[KindNamespacedName]adminPath) (KindNamespacedName, bool) {
+	targets := []KindNamespacedName{}
+	for target := range paths {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return len(paths[targets[i]]) < len(paths[targets[j]]) ||
+			(len(paths[targets[i]]) == len(paths[targets[j]]) && targets[i].kind+targets[i].qualifiedName() < targets[j].kind+targets[j].qualifiedName())
+	})
+	for _, kind := range f.rule.impersonatedKinds() {
+		for _, target := range targets {
+			if target.kind != kind || target == f.subject {
+				continue
+			}
+			if kind == "ServiceAccount" && f.binding.namespace != "" && target.namespace != f.binding.namespace {
+				continue
+			}
+			if len(f.rule.resourceNames) == 0 || contains(f.rule.resourceNames, target.name) {
+				return target, true
+			}
+		}
+	}
+	return KindNamespacedName{}, false
+}
+
+// adminPathReportSection lists the subjects with a path to cluster-admin, and logs those without one as safe
+func (r *Rback) adminPathReportSection() reportSection {
+	paths := r.findAdminPaths()
+	section := emptyReportSection(codePathClusterAdmin, "Subjects with a path to cluster-admin", severityHigh)
+	found := []KindNamespacedName{}
+	safe := []string{}
+	for _, subject := range r.boundSubjects() {
+		if _, ok := paths[subject]; ok {
+			found = append(found, subject)
+		} else {
+			safe = append(safe, subject.kind+" "+subject.qualifiedName())
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return paths[found[i]].String() < paths[found[j]].String()
+	})
+	for _, subject := range found {
+		section.add(subject.kind+" "+subject.qualifiedName(), paths[subject][0].binding.namespace, paths[subject].String())
+	}
+	if len(safe) > 0 {
+		infof("%d subjects have no path to cluster-admin (safe): %s", len(safe), strings.Join(safe, ", "))
+	}
+	return section
+}
+
+// boundSubjects returns the subjects of the bindings in the selected namespaces, sorted by kind and name
+func (r *Rback) boundSubjects() []KindNamespacedName {
+	seen := map[KindNamespacedName]bool{}
+	subjects := []KindNamespacedName{}
+	for ns, bindings := range r.permissions.RoleBindings {
+		if ns != "" && !r.namespaceSelected(ns) {
+			continue
+		}
+		for _, binding := range bindings {
+			for _, subject := range binding.subjects {
+				if !seen[subject] {
+					seen[subject] = true
+					subjects = append(subjects, subject)
+				}
+			}
+		}
+	}
+	sort.Slice(subjects, func(i, j int) bool {
+		return subjects[i].kind+subjects[i].qualifiedName() < subjects[j].kind+subjects[j].qualifiedName()
+	})
+	return subjects
+}
+
+// pathGrant identifies the edge from a subject to a binding that's part of a path to cluster-admin
+type pathGrant struct {
+	subject KindNamespacedName
+	binding NamespacedName
+}
+
+// findPathGrants returns the edges from subjects to bindings that are part of any path to cluster-admin, which are the
+// only ones rendered with -path-to
+func (r *Rback) findPathGrants() map[pathGrant]bool {
+	grants := map[pathGrant]bool{}
+	for _, path := range r.findAdminPaths() {
+		for _, f := range path {
+			grants[pathGrant{f.subject, f.binding}] = true
+		}
+	}
+	return grants
+}
+
+// onPath returns true if the subject's binding is rendered with -path-to (always true without it)
+func (r *Rback) onPath(subject KindNamespacedName, binding NamespacedName) bool {
+	return r.pathGrants == nil || r.pathGrants[pathGrant{subject, binding}]
+}
+
+// bindingOnPath returns true if any of the binding's subjects reach cluster-admin through it (always true without -path-to)
+func (r *Rback) bindingOnPath(binding Binding) bool {
+	for _, subject := range binding.subjects {
+		if r.onPath(subject, binding.NamespacedName) {
+			return true
+		}
+	}
+	return r.pathGrants == nil
+}
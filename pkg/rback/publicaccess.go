@@ -0,0 +1,49 @@
+package rback
+
+import "sort"
+
+// the groups that Kubernetes puts all authenticated and all unauthenticated requests into
+const (
+	authenticatedGroup   = "system:authenticated"
+	unauthenticatedGroup = "system:unauthenticated"
+)
+
+// publicSubject returns true if the subject stands for everyone (who can reach the API server): all authenticated or
+// all unauthenticated users, or the anonymous user of unauthenticated requests
+func publicSubject(subject KindNamespacedName) bool {
+	return unauthenticatedSubject(subject) || (subject.kind == "Group" && subject.name == authenticatedGroup)
+}
+
+// unauthenticatedSubject returns true if the subject stands for unauthenticated requests, which are granted anything
+// almost only by mistake
+func unauthenticatedSubject(subject KindNamespacedName) bool {
+	return (subject.kind == "Group" && subject.name == unauthenticatedGroup) || (subject.kind == "User" && subject.name == anonymousUser)
+}
+
+// findPublicAccessGrants finds the grants to all authenticated or all unauthenticated users
+func (r *Rback) findPublicAccessGrants() []finding {
+	return r.findSubjectGrants(func(binding Binding, subject KindNamespacedName, rule Rule) bool {
+		return publicSubject(subject)
+	})
+}
+
+// unauthenticatedBindings returns the descriptions of the bindings in the selected namespaces that grant a role to
+// unauthenticated users, sorted
+func (r *Rback) unauthenticatedBindings() []string {
+	descriptions := []string{}
+	for ns, bindings := range r.permissions.RoleBindings {
+		if ns != "" && !r.namespaceSelected(ns) {
+			continue
+		}
+		for _, binding := range bindings {
+			for _, subject := range binding.subjects {
+				if unauthenticatedSubject(subject) {
+					f := finding{subject: subject, binding: binding.NamespacedName, role: binding.role}
+					descriptions = append(descriptions, f.bindingDescription()+" grants "+f.roleDescription()+" to "+subject.kind+" "+subject.name)
+				}
+			}
+		}
+	}
+	sort.Strings(descriptions)
+	return descriptions
+}
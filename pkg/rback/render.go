@@ -0,0 +1,1046 @@
+package rback
+
+import (
+	"fmt"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emicklei/dot"
+)
+
+func (r *Rback) genGraph() *dot.Graph {
+	g := newGraph()
+	r.model = newGraphModel()
+	r.focused = nil
+	r.orphans = nil
+	r.pathGrants = nil
+	r.rulesNodes = map[string]*dot.Node{}
+	r.namespaceGraphs = map[string]*dot.Graph{}
+	r.resetEffectiveRules()
+	if r.config.focus.enabled() {
+		r.focused = r.findFocusedResources()
+	}
+	if r.config.markOrphans {
+		r.orphans = make(map[NamespacedName]bool)
+		for _, sa := range r.findOrphanServiceAccounts() {
+			r.orphans[sa] = true
+		}
+	}
+	if r.config.pathTo != "" {
+		r.pathGrants = r.findPathGrants()
+	}
+	_, _, bindingCount := r.permissions.counts()
+	r.progressf("Rendering %d (Cluster)RoleBindings", bindingCount)
+	defer func() {
+		r.progressf("Rendered %d nodes and %d edges", len(r.model.nodes), len(r.model.edges))
+	}()
+	r.renderTitleAndCaption(g)
+	r.applyLayout(g)
+	defer r.renderLegend(g) // last, so that -legend-present-only knows what was rendered
+	defer r.renderOverview(g)
+
+	// the maps of resources are rendered in order: the nodes are numbered in the order they're created, so the output
+	// would differ between runs otherwise
+	for _, binding := range sortedBindings(r.permissions.RoleBindings) {
+		if !r.shouldRenderBinding(binding) {
+			continue
+		}
+
+		gns := r.newNamespaceSubgraph(g, binding.namespace)
+
+		var bindingNode dot.Node
+		if r.compact() {
+			bindingNode = r.newCompactBindingNode(gns, binding)
+		} else {
+			bindingNode = r.newBindingNode(gns, binding)
+			if !r.config.bindingsOnly {
+				roleNode := r.newRoleAndRulesNodePair(gns, binding.namespace, binding.role)
+				r.newBindingToRoleEdge(bindingNode, roleNode)
+				r.model.addEdge(r.bindingNodeID(binding), r.roleNodeID(binding.namespace, binding.role))
+			}
+		}
+
+		saNodes := []dot.Node{}
+		for _, subject := range binding.subjects {
+			renderSubject := (r.config.resourceKind != kindServiceAccount) ||
+				(r.namespaceSelected(subject.namespace) && r.resourceNameSelected(subject.name))
+			if subject.kind == "ServiceAccount" && !r.namespaceSelected(subject.namespace) {
+				renderSubject = false // only happens for ClusterRoleBindings, which can bind ServiceAccounts in any namespace
+			}
+			// the virtual ServiceAccount groups are rendered in the namespace of their members
+			subjectNs := subject.namespace
+			if groupNs, ok := serviceAccountsGroupNamespace(subject); ok {
+				subjectNs = groupNs
+				if r.config.resourceKind == kindServiceAccount {
+					renderSubject = groupNs == "" || r.namespaceSelected(groupNs)
+				} else if groupNs != "" && !r.namespaceSelected(groupNs) {
+					renderSubject = false // like ServiceAccounts in namespaces that aren't selected
+				}
+			}
+			if !r.subjectKindSelected(subject.kind) || !r.onPath(subject, binding.NamespacedName) {
+				renderSubject = false
+			}
+			if r.config.resourceKind == kindIdentity && !r.config.identity.includes(subject) {
+				renderSubject = false // only the identity is the root of the graph, not others bound along with it
+			}
+			if subject.kind == "ServiceAccount" && r.hideDefaultServiceAccount(subject.namespace, subject.name) {
+				renderSubject = false
+			}
+
+			if renderSubject {
+				gns := r.newNamespaceSubgraph(g, subjectNs)
+				subjectNode := r.newSubjectNode(gns, subject.kind, subject.namespace, subject.name)
+				saNodes = append(saNodes, subjectNode)
+				r.model.addEdge(subjectNodeID(subject.kind, subject.namespace, subject.name), r.bindingNodeID(binding))
+			}
+		}
+
+		for _, saNode := range saNodes {
+			r.labelEdge(newSubjectToBindingEdge(saNode, bindingNode), binding)
+		}
+	}
+
+	// draw any additional ServiceAccounts that weren't referenced by bindings (and thus drawn in the code above)
+	// (with -since, only ServiceAccounts bound by recently created bindings are rendered, and with -only-annotated, only
+	// those bound by annotated bindings or annotated themselves)
+	if (r.config.resourceKind == "" || r.config.resourceKind == kindServiceAccount) && r.subjectKindSelected("ServiceAccount") && r.config.since == 0 &&
+		r.pathGrants == nil && !r.config.clusterWideOnly {
+		namespaces := []string{}
+		for ns := range r.permissions.ServiceAccounts {
+			if r.namespaceSelected(ns) {
+				namespaces = append(namespaces, ns)
+			}
+		}
+		sort.Strings(namespaces)
+
+		for _, ns := range namespaces {
+			gns := r.newNamespaceSubgraph(g, ns)
+
+			sas := []string{}
+			for sa := range r.permissions.ServiceAccounts[ns] {
+				sas = append(sas, sa)
+			}
+			sort.Strings(sas)
+
+			for _, sa := range sas {
+				account := r.permissions.ServiceAccounts[ns][sa]
+				renderSA := r.config.resourceKind == "" || (r.namespaceSelected(ns) && r.resourceNameSelected(sa))
+				if !r.annotated(account.annotations) {
+					renderSA = false
+				}
+				if r.muteDefaultServiceAccount(sa) {
+					renderSA = false // those with non-default bindings are still rendered (muted) along with these
+				}
+				if renderSA {
+					r.newSubjectNode(gns, "ServiceAccount", ns, sa)
+				}
+			}
+		}
+	}
+
+	if r.config.showSASecrets {
+		r.renderServiceAccountSecrets(g)
+	}
+
+	if r.config.showImpersonation {
+		r.renderImpersonation(g)
+	}
+
+	if r.config.collapseIgnored {
+		r.renderIgnoredSummaries(g)
+	}
+
+	if r.config.bindingsOnly {
+		return g
+	}
+
+	// draw any additional Roles that weren't referenced by bindings (and thus already drawn); they aren't connected to
+	// any subjects, so they're left out when filtering by -subject-kind or -path-to
+	namespaces := []string{}
+	for ns := range r.permissions.Roles {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		if len(r.config.subjectKinds) > 0 || r.pathGrants != nil {
+			break
+		}
+		var renderRoles bool
+
+		areClusterRoles := ns == ""
+		if areClusterRoles {
+			renderRoles = (r.config.resourceKind == "" || r.config.resourceKind == kindClusterRole) && r.allNamespaces()
+		} else {
+			renderRoles = (r.config.resourceKind == "" || r.config.resourceKind == kindRole) && r.namespaceSelected(ns) && !r.config.clusterWideOnly
+		}
+
+		if !renderRoles {
+			continue
+		}
+
+		gns := r.newNamespaceSubgraph(g, ns)
+		roleNames := []string{}
+		for roleName := range r.permissions.Roles[ns] {
+			roleNames = append(roleNames, roleName)
+		}
+		sort.Strings(roleNames)
+
+		for _, roleName := range roleNames {
+			role := r.permissions.Roles[ns][roleName]
+			renderRole := r.namespaceSelected(ns) && r.resourceNameSelected(roleName) && r.createdRecently(role.created) &&
+				r.annotated(role.annotations) && r.hasShownRules(role.NamespacedName)
+			if renderRole {
+				r.newRoleAndRulesNodePair(gns, "", NamespacedName{ns, roleName})
+			}
+		}
+	}
+
+	if r.config.showDefaultRoleHierarchy {
+		r.renderDefaultRoleHierarchy(g)
+	}
+
+	return g
+}
+
+// sortedBindings returns the bindings ordered by namespace and name
+func sortedBindings(bindingsByNamespace map[string]map[string]Binding) []Binding {
+	sorted := []Binding{}
+	for _, bindings := range bindingsByNamespace {
+		for _, binding := range bindings {
+			sorted = append(sorted, binding)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].namespace != sorted[j].namespace {
+			return sorted[i].namespace < sorted[j].namespace
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	return sorted
+}
+
+// applyLayout sets the graph attributes given by -orientation, -ranksep, -nodesep and -splines (leaving Graphviz'
+// defaults otherwise), and then those given by -graph-attr, which override any others (including newrank)
+func (r *Rback) applyLayout(g *dot.Graph) {
+	if r.config.orientation == orientationRoleFirst {
+		g.Attr("rankdir", "BT") // roles at the top, and the subjects using them below
+	}
+	if r.config.rankSep > 0 {
+		g.Attr("ranksep", strconv.FormatFloat(r.config.rankSep, 'f', -1, 64))
+	}
+	if r.config.nodeSep > 0 {
+		g.Attr("nodesep", strconv.FormatFloat(r.config.nodeSep, 'f', -1, 64))
+	}
+	if r.config.splines != "" {
+		g.Attr("splines", r.config.splines)
+	}
+	for _, attr := range r.config.graphAttrs {
+		g.Attr(attr[0], attr[1])
+	}
+}
+
+func (r *Rback) newNamespaceSubgraph(g *dot.Graph, ns string) *dot.Graph {
+	if r.config.groupByLabel != "" && ns != "" {
+		g = r.newGroupSubgraph(g, ns)
+	}
+	gns := newNamespaceSubgraph(g, ns)
+	if r.config.colorNamespaces && ns != "" {
+		colorNamespaceSubgraph(gns, ns)
+	}
+	if ns != "" {
+		r.namespaceGraphs[ns] = gns
+	}
+	return gns
+}
+
+// newGroupSubgraph returns the cluster that the namespace is grouped into by the value of its -group-by-label label
+func (r *Rback) newGroupSubgraph(g *dot.Graph, ns string) *dot.Graph {
+	value, found := r.permissions.NamespaceLabels[ns][r.config.groupByLabel]
+	if !found {
+		return newGroupSubgraph(g, "ungrouped")
+	}
+	return newGroupSubgraph(g, r.config.groupByLabel+": "+value)
+}
+
+// newBindingToRoleEdge connects a binding to its role. With -orientation role-first, the graph is ranked bottom to top
+// (see applyLayout), and the arrow points from the role to the binding, so that the graph reads from roles to subjects.
+func (r *Rback) newBindingToRoleEdge(bindingNode dot.Node, roleNode dot.Node) dot.Edge {
+	e := newBindingToRoleEdge(bindingNode, roleNode)
+	if r.config.orientation == orientationRoleFirst {
+		e.Attr("dir", "back")
+	}
+	return e
+}
+
+// newRoleToRulesEdge connects a role to its rules. With -orientation role-first, the edge is reversed (keeping the
+// arrow at the rules), so that the rules are ranked below their role rather than above it.
+func (r *Rback) newRoleToRulesEdge(roleNode dot.Node, rulesNode dot.Node) dot.Edge {
+	if r.config.orientation == orientationRoleFirst {
+		return edge(rulesNode, roleNode).Attr("dir", "back")
+	}
+	return newRoleToRulesEdge(roleNode, rulesNode)
+}
+
+// labelEdge labels an edge from a subject to a binding as selected by -edge-label, e.g. to show the role when it isn't
+// rendered (with -bindings-only)
+func (r *Rback) labelEdge(e dot.Edge, binding Binding) {
+	switch r.config.edgeLabel {
+	case edgeLabelBinding:
+		e.Attr("label", binding.name)
+	case edgeLabelRole:
+		e.Attr("label", binding.role.name)
+	case edgeLabelKind:
+		e.Attr("label", iff(binding.role.namespace == "", "ClusterRole", "Role"))
+	}
+}
+
+// applyURL makes the node a link (e.g. in SVG output) to the URL built from -url-template
+func (r *Rback) applyURL(node dot.Node, kind, ns, name string) {
+	if r.config.urlTemplate == "" {
+		return
+	}
+	url := strings.NewReplacer(
+		"{kind}", neturl.PathEscape(kind),
+		"{namespace}", neturl.PathEscape(ns),
+		"{name}", neturl.PathEscape(name),
+	).Replace(r.config.urlTemplate)
+	node.Attr("URL", url)
+}
+
+func (r *Rback) renderTitleAndCaption(g *dot.Graph) {
+	if r.config.title != "" {
+		setGraphTitle(g, r.config.title)
+	}
+	if r.config.caption != "" {
+		newCaptionNode(g, r.config.caption)
+	}
+}
+
+// genLegend renders just the legend as a graph of its own, for -legend-only
+func (r *Rback) genLegend() *dot.Graph {
+	g := newGraph()
+	r.applyLayout(g)
+	r.renderLegend(g)
+	return g
+}
+
+func (r *Rback) renderLegend(g *dot.Graph) {
+	if !r.config.showLegend {
+		return
+	}
+
+	show := r.legendEntries()
+	legend := g.Subgraph("LEGEND", dot.ClusterOption{})
+
+	namespace := newNamespaceSubgraph(legend, "Namespace")
+
+	sa := newSubjectNode0(namespace, "Kind", "", "Subject", true, false)
+	var missingSa *dot.Node
+	if show[legendMissingSubject] {
+		node := newSubjectNode0(namespace, "Kind", "", "Missing Subject", false, false)
+		missingSa = &node
+	}
+
+	if show[legendRoleBinding] {
+		role := newRoleNode(namespace, "ns", "Role", true, false)
+		roleBinding := newRoleBindingNode(namespace, "Namespace", "RoleBinding", false)
+		newSubjectToBindingEdge(sa, roleBinding)
+		if missingSa != nil {
+			newSubjectToBindingEdge(*missingSa, roleBinding)
+		}
+		r.newBindingToRoleEdge(roleBinding, role)
+		if r.config.showRules {
+			nsrules := newRulesNode0(namespace, kindRole, "ns", "Role", "Namespace-scoped\naccess rules", false, false)
+			r.newRoleToRulesEdge(role, nsrules)
+		}
+	}
+
+	if show[legendRoleBindingToClusterRole] {
+		clusterRoleBoundLocally := newClusterRoleNode(namespace, "ns", "ClusterRole", true, false) // bound by (namespaced!) RoleBinding
+		roleBinding2 := newRoleBindingNode(namespace, "Namespace", "RoleBinding-to-ClusterRole", false)
+		roleBinding2.Attr("label", "RoleBinding")
+		newSubjectToBindingEdge(sa, roleBinding2)
+		r.newBindingToRoleEdge(roleBinding2, clusterRoleBoundLocally)
+		if r.config.showRules {
+			nsrules2 := newRulesNode0(namespace, kindClusterRole, "ns", "ClusterRole", "Namespace-scoped access rules From ClusterRole", false, false)
+			nsrules2.Attr("label", "Namespace-scoped\naccess rules")
+			r.newRoleToRulesEdge(clusterRoleBoundLocally, nsrules2)
+		}
+	}
+
+	if show[legendClusterRoleBinding] {
+		clusterrole := newClusterRoleNode(legend, "", "ClusterRole", true, false)
+		clusterRoleBinding := newClusterRoleBindingNode(legend, "ClusterRoleBinding", false)
+		newSubjectToBindingEdge(sa, clusterRoleBinding)
+		r.newBindingToRoleEdge(clusterRoleBinding, clusterrole)
+		if r.config.showRules {
+			clusterrules := newRulesNode0(legend, kindClusterRole, "", "ClusterRole", "Cluster-scoped\naccess rules", true, false)
+			r.newRoleToRulesEdge(clusterrole, clusterrules)
+		}
+	}
+}
+
+// the optional entries of the legend
+const (
+	legendMissingSubject           = "missing-subject"
+	legendRoleBinding              = "rolebinding"
+	legendRoleBindingToClusterRole = "rolebinding-to-clusterrole"
+	legendClusterRoleBinding       = "clusterrolebinding"
+)
+
+// legendEntries returns the entries of the legend to render: all of them, unless -legend-present-only is set, in which
+// case only those for the kinds of nodes and edges that were rendered (so the legend needs to be rendered last)
+func (r *Rback) legendEntries() map[string]bool {
+	all := !r.config.legendPresentOnly || r.config.legendOnly
+	show := map[string]bool{
+		legendMissingSubject:           all,
+		legendRoleBinding:              all,
+		legendRoleBindingToClusterRole: all,
+		legendClusterRoleBinding:       all,
+	}
+	kinds := map[string]string{}
+	for _, n := range r.model.nodes {
+		kinds[n.id] = n.kind
+		switch n.kind {
+		case "ServiceAccount", "User", "Group":
+			show[legendMissingSubject] = show[legendMissingSubject] || !r.subjectExists(n.kind, n.namespace, n.label)
+		case "ClusterRoleBinding":
+			show[legendClusterRoleBinding] = true
+		}
+	}
+	for _, e := range r.model.edges {
+		if kinds[e.source] == "RoleBinding" {
+			show[legendRoleBinding] = show[legendRoleBinding] || kinds[e.target] == "Role"
+			show[legendRoleBindingToClusterRole] = show[legendRoleBindingToClusterRole] || kinds[e.target] == "ClusterRole"
+		}
+	}
+	return show
+}
+
+// subjectKindSelected returns true if subjects of the given kind (e.g. ServiceAccount) are selected via -subject-kind
+func (r *Rback) subjectKindSelected(kind string) bool {
+	return len(r.config.subjectKinds) == 0 || contains(r.config.subjectKinds, strings.ToLower(kind))
+}
+
+// bindsSelectedSubjectKind returns true if the binding binds any subject of a kind selected via -subject-kind
+func (r *Rback) bindsSelectedSubjectKind(binding Binding) bool {
+	if len(r.config.subjectKinds) == 0 {
+		return true
+	}
+	for _, subject := range binding.subjects {
+		if r.subjectKindSelected(subject.kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleShown returns false for rules that are filtered out: those that only grant read-only verbs, when rendering
+// -writes-only, and those restricted to resourceNames other than the one given by -resource-name
+func (r *Rback) ruleShown(rule Rule) bool {
+	if r.config.writesOnly && rule.onlyGrants(r.config.readOnlyVerbs) {
+		return false
+	}
+	if r.config.resourceName != "" && !rule.appliesToResourceName(r.config.resourceName) {
+		return false
+	}
+	return true
+}
+
+// shownRules returns the rules that are shown (see ruleShown), without the resources hidden via -hide-resources and the
+// verbs hidden via -hide-verbs. Rules that are left without any resources or verbs are dropped.
+func (r *Rback) shownRules(rules []Rule) []Rule {
+	shown := []Rule{}
+	for _, rule := range rules {
+		if !r.ruleShown(rule) {
+			continue
+		}
+		if len(r.config.hiddenResources) > 0 && len(rule.resources) > 0 {
+			resources := []string{}
+			for _, resource := range rule.resources {
+				if !r.resourceHidden(rule, resource) {
+					resources = append(resources, resource)
+				}
+			}
+			if len(resources) == 0 {
+				continue
+			}
+			rule.resources = resources
+		}
+		if len(r.config.hiddenVerbs) > 0 {
+			verbs := []string{}
+			for _, verb := range rule.verbs {
+				if !contains(r.config.hiddenVerbs, verb) {
+					verbs = append(verbs, verb)
+				}
+			}
+			if len(verbs) == 0 {
+				continue
+			}
+			rule.verbs = verbs
+		}
+		shown = append(shown, rule)
+	}
+	return shown
+}
+
+// resourceHidden returns true if the resource of the rule is hidden via -hide-resources, either by its name alone or
+// qualified with an API group the rule applies to (e.g. leases.coordination.k8s.io)
+func (r *Rback) resourceHidden(rule Rule, resource string) bool {
+	for _, hidden := range r.config.hiddenResources {
+		hiddenResource, apiGroup := splitGroupResource(hidden)
+		if hiddenResource == resource && rule.matchesAPIGroup(apiGroup) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasShownRules returns false for roles whose rules are all filtered out (see ruleShown). Roles that don't exist are
+// kept, since their bindings are worth noticing anyway.
+func (r *Rback) hasShownRules(roleRef NamespacedName) bool {
+	role, found := r.lookupRole(roleRef)
+	if (!r.config.writesOnly && r.config.resourceName == "") || !found {
+		return true
+	}
+	for _, rule := range role.rules {
+		if r.ruleShown(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// createdRecently returns true if a resource was created within the duration given by -since (or if it isn't set)
+func (r *Rback) createdRecently(created time.Time) bool {
+	return r.config.since == 0 || created.After(r.config.now.Add(-r.config.since))
+}
+
+func (r *Rback) shouldRenderBinding(binding Binding) bool {
+	if len(binding.subjects) == 0 && !r.config.showEmptyBindings {
+		return false
+	}
+	if !r.bindsSelectedSubjectKind(binding) {
+		return false
+	}
+	if !r.createdRecently(binding.created) || !r.annotated(binding.annotations) {
+		return false
+	}
+	if !r.hasShownRules(binding.role) {
+		return false
+	}
+	if !r.bindingOnPath(binding) {
+		return false
+	}
+
+	switch r.config.resourceKind {
+	case "":
+		if r.config.clusterWideOnly {
+			return binding.namespace == ""
+		}
+		// a ClusterRoleBinding is relevant to the selected namespaces if it grants permissions to a ServiceAccount in them
+		return r.namespaceSelected(binding.namespace) || (binding.namespace == "" && r.bindsServiceAccountInSelectedNamespace(binding))
+	case kindRoleBinding:
+		return r.namespaceSelected(binding.namespace) && r.resourceNameSelected(binding.name)
+	case kindClusterRoleBinding:
+		return binding.namespace == "" && r.resourceNameSelected(binding.name)
+	case kindServiceAccount:
+		for _, subject := range binding.subjects {
+			if subject.kind == "ServiceAccount" &&
+				r.namespaceSelected(subject.namespace) &&
+				r.resourceNameSelected(subject.name) &&
+				r.subjectExists("ServiceAccount", subject.namespace, subject.name) {
+				return true
+			}
+			if r.groupContainsSelectedServiceAccount(subject) {
+				return true
+			}
+		}
+	case kindUser:
+		for _, subject := range binding.subjects {
+			if subject.kind == "User" && r.resourceNameSelected(subject.name) {
+				return true
+			}
+		}
+	case kindGroup:
+		for _, subject := range binding.subjects {
+			if subject.kind == "Group" && r.resourceNameSelected(subject.name) {
+				return true
+			}
+		}
+	case kindRole:
+		bindingPointsToClusterRole := binding.role.namespace == ""
+		return !bindingPointsToClusterRole &&
+			r.namespaceSelected(binding.role.namespace) &&
+			r.resourceNameSelected(binding.role.name) &&
+			r.roleExists(binding.role)
+	case kindClusterRole:
+		bindingPointsToClusterRole := binding.role.namespace == ""
+		return bindingPointsToClusterRole &&
+			r.resourceNameSelected(binding.role.name) &&
+			r.roleExists(binding.role)
+	case kindIdentity:
+		return r.bindsIdentity(binding)
+	case kindRule:
+		bindingPointsToClusterRole := binding.role.namespace == ""
+		return r.ruleMatchesSelection(binding.role) && (bindingPointsToClusterRole || r.namespaceSelected(binding.role.namespace))
+	}
+	return false
+}
+
+func (r *Rback) bindsServiceAccountInSelectedNamespace(binding Binding) bool {
+	for _, subject := range binding.subjects {
+		if subject.kind == "ServiceAccount" && r.namespaceSelected(subject.namespace) {
+			return true
+		}
+		if groupNs, ok := serviceAccountsGroupNamespace(subject); ok && (groupNs == "" || r.namespaceSelected(groupNs)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rback) newBindingNode(gns *dot.Graph, binding Binding) dot.Node {
+	var node dot.Node
+	if binding.namespace == "" {
+		node = newClusterRoleBindingNode(gns, binding.name, r.isFocused(kindClusterRoleBinding, "", binding.name))
+		r.applyLabelTemplate(node, "ClusterRoleBinding", "", binding.name, r.isFocused(kindClusterRoleBinding, "", binding.name))
+	} else {
+		node = newRoleBindingNode(gns, binding.namespace, binding.name, r.isFocused(kindRoleBinding, binding.namespace, binding.name))
+		r.applyLabelTemplate(node, "RoleBinding", binding.namespace, binding.name, r.isFocused(kindRoleBinding, binding.namespace, binding.name))
+	}
+	applyNodeColors(node, binding.colors)
+	r.applyFocus(node, r.focused != nil && r.focused.bindings[binding.NamespacedName])
+	r.model.addNode(r.bindingNodeID(binding), iff(binding.namespace == "", "ClusterRoleBinding", "RoleBinding"), binding.namespace, binding.name)
+	r.applyURL(node, iff(binding.namespace == "", kindClusterRoleBinding, kindRoleBinding), binding.namespace, binding.name)
+	return node
+}
+
+func (r *Rback) bindingNodeID(binding Binding) string {
+	if r.compact() {
+		return compactBindingNodeID(binding.namespace, binding.name)
+	}
+	if binding.namespace == "" {
+		return clusterRoleBindingNodeID(binding.name)
+	}
+	return roleBindingNodeID(binding.namespace, binding.name)
+}
+
+// compact returns true if bindings and their roles are rendered as a single node (-compact), which doesn't apply
+// when only rendering bindings anyway
+func (r *Rback) compact() bool {
+	return r.config.compact && !r.config.bindingsOnly
+}
+
+// newCompactBindingNode renders a binding and its role as a single node (along with the role's rules), for -compact
+func (r *Rback) newCompactBindingNode(gns *dot.Graph, binding Binding) dot.Node {
+	bindingKind := iff(binding.namespace == "", kindClusterRoleBinding, kindRoleBinding)
+	roleKind := iff(binding.role.namespace == "", kindClusterRole, kindRole)
+	highlight := r.isFocused(bindingKind, binding.namespace, binding.name) || r.isFocused(roleKind, binding.role.namespace, binding.role.name)
+	id := r.bindingNodeID(binding)
+	node := newCompactBindingNode0(gns, id, binding.name, binding.role.name, binding.namespace == "", r.roleExists(binding.role), highlight)
+	applyNodeColors(node, binding.colors)
+	inFocus := r.focused != nil && (r.focused.bindings[binding.NamespacedName] || r.focused.roles[binding.role])
+	r.applyFocus(node, inFocus)
+	r.model.addNode(id, iff(binding.namespace == "", "ClusterRoleBinding", "RoleBinding"), binding.namespace, binding.name+" → "+binding.role.name)
+	r.applyURL(node, bindingKind, binding.namespace, binding.name)
+	if r.config.showRules && !contains(r.config.noRulesFor, binding.role.name) {
+		rulesGraph, rulesNamespace := r.rulesPlacement(gns, binding.namespace, binding.role)
+		rulesNode := r.newRulesNode(rulesGraph, rulesNamespace, binding.role, r.isFocused(kindRule, binding.role.namespace, binding.role.name))
+		if rulesNode != nil {
+			r.applyFocus(*rulesNode, inFocus)
+			r.newRoleToRulesEdge(node, *rulesNode)
+			r.model.addEdge(id, r.rulesNodeID(rulesNamespace, binding.role))
+		}
+	}
+	return node
+}
+
+// roleNodeID returns the ID of the node rendered by newRoleAndRulesNodePair for the given role
+func (r *Rback) roleNodeID(bindingNamespace string, role NamespacedName) string {
+	if role.namespace != "" {
+		return roleNodeID(role.namespace, role.name)
+	}
+	if r.config.mergeClusterRoles {
+		return clusterRoleNodeID("", role.name)
+	}
+	return clusterRoleNodeID(bindingNamespace, role.name)
+}
+
+func (r *Rback) rulesNodeID(bindingNamespace string, role NamespacedName) string {
+	if role.namespace == "" {
+		return rulesNodeID(kindClusterRole, bindingNamespace, role.name)
+	}
+	return rulesNodeID(kindRole, role.namespace, role.name)
+}
+
+func (r *Rback) newRoleAndRulesNodePair(gns *dot.Graph, bindingNamespace string, role NamespacedName) dot.Node {
+	var roleNode dot.Node
+	if role.namespace == "" {
+		if r.config.mergeClusterRoles {
+			// a single ClusterRole node is shared by all bindings, while the rules stay in the binding's namespace
+			roleNode = newClusterRoleNode(gns.Root(), "", role.name, r.roleExists(role), r.isFocused(kindClusterRole, role.namespace, role.name))
+		} else {
+			roleNode = newClusterRoleNode(gns, bindingNamespace, role.name, r.roleExists(role), r.isFocused(kindClusterRole, role.namespace, role.name))
+		}
+	} else {
+		if role.namespace != bindingNamespace {
+			gns = r.newNamespaceSubgraph(gns.Root(), role.namespace) // a roleRef with a namespace (see toBinding)
+		}
+		roleNode = newRoleNode(gns, role.namespace, role.name, r.roleExists(role), r.isFocused(kindRole, role.namespace, role.name))
+	}
+	r.applyLabelTemplate(roleNode, iff(role.namespace == "", "ClusterRole", "Role"), role.namespace, role.name,
+		r.isFocused(iff(role.namespace == "", kindClusterRole, kindRole), role.namespace, role.name))
+	if found, ok := r.lookupRole(role); ok {
+		applyNodeColors(roleNode, found.colors)
+	}
+	inFocus := r.focused != nil && r.focused.roles[role]
+	r.applyFocus(roleNode, inFocus)
+	roleNodeID := r.roleNodeID(bindingNamespace, role)
+	r.model.addNode(roleNodeID, iff(role.namespace == "", "ClusterRole", "Role"), role.namespace, role.name)
+	r.applyURL(roleNode, iff(role.namespace == "", kindClusterRole, kindRole), role.namespace, role.name)
+	if r.config.showRules && !contains(r.config.noRulesFor, role.name) {
+		rulesGraph, rulesNamespace := r.rulesPlacement(gns, bindingNamespace, role)
+		rulesNode := r.newRulesNode(rulesGraph, rulesNamespace, role, r.isFocused(kindRule, role.namespace, role.name))
+		if rulesNode != nil {
+			r.applyFocus(*rulesNode, inFocus)
+			r.newRoleToRulesEdge(roleNode, *rulesNode)
+			r.model.addEdge(roleNodeID, r.rulesNodeID(rulesNamespace, role))
+		}
+	}
+	return roleNode
+}
+
+// rulesPlacement returns the graph and binding namespace to render the rules of a role in. With
+// -merge-rules-across-bindings, the rules of a ClusterRole are rendered once, outside of any namespace, rather than once
+// per namespace it's bound in.
+func (r *Rback) rulesPlacement(gns *dot.Graph, bindingNamespace string, role NamespacedName) (*dot.Graph, string) {
+	if r.config.mergeRules && role.namespace == "" {
+		return gns.Root(), ""
+	}
+	return gns, bindingNamespace
+}
+
+// boundClusterWide returns true if the ClusterRole is bound by any ClusterRoleBinding, i.e. its rules apply in all
+// namespaces
+func (r *Rback) boundClusterWide(role NamespacedName) bool {
+	for _, binding := range r.permissions.RoleBindings[""] {
+		if binding.role == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rback) roleExists(role NamespacedName) bool {
+	_, exists := r.lookupRole(role)
+	return exists
+}
+
+// lookupRole finds a role by namespace and name in constant time, since parseRBAC indexes the (decoded) roles that way.
+// All role lookups should go through it, rather than scanning r.permissions.Roles.
+func (r *Rback) lookupRole(roleRef NamespacedName) (Role, bool) {
+	if roles, nsExists := r.permissions.Roles[roleRef.namespace]; nsExists {
+		if role, roleExists := roles[roleRef.name]; roleExists {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+func (r *Rback) newSubjectNode(gns *dot.Graph, kind string, ns string, name string) dot.Node {
+	exists := r.subjectExists(kind, ns, name)
+	highlight := r.isFocused(strings.ToLower(kind), ns, name)
+	node := newSubjectNode0(gns, kind, ns, name, exists, highlight)
+	r.applyLabelTemplate(node, kind, ns, name, highlight)
+	if _, ok := serviceAccountsGroupNamespace(KindNamespacedName{kind, NamespacedName{ns, name}}); ok {
+		markServiceAccountsGroup(node, r.subjectLabel(kind, ns, name), highlight)
+	}
+	if publicSubject(KindNamespacedName{kind, NamespacedName{ns, name}}) {
+		markPublicAccess(node)
+	}
+	if r.config.colorNamespaces && ns != "" && exists {
+		node.Attr("color", namespaceColor(ns).border)
+	}
+	label := r.subjectLabel(kind, ns, name)
+	if r.config.showPermissionCount && strings.ToLower(kind) == kindServiceAccount {
+		label = fmt.Sprintf("%s\n[%d permissions]", label, r.permissionCount(KindNamespacedName{kind, NamespacedName{ns, name}}))
+		node.Attr("label", formatLabel(label, highlight))
+	}
+	if r.config.showAutomount && strings.ToLower(kind) == kindServiceAccount && exists &&
+		!r.permissions.ServiceAccounts[ns][name].automountToken {
+		markTokenNotAutomounted(node, label, highlight)
+	}
+	if strings.ToLower(kind) == kindServiceAccount && exists {
+		applyNodeColors(node, r.permissions.ServiceAccounts[ns][name].colors)
+	}
+	if r.orphans != nil && r.orphans[NamespacedName{ns, name}] && strings.ToLower(kind) == kindServiceAccount {
+		dimNode(node)
+	}
+	if strings.ToLower(kind) == kindServiceAccount && r.muteDefaultServiceAccount(name) {
+		dimNode(node)
+	}
+	r.applyFocus(node, r.focused != nil && r.focused.subjects[KindNamespacedName{kind, NamespacedName{ns, name}}])
+	r.model.addNode(subjectNodeID(kind, ns, name), kind, ns, name)
+	r.applyURL(node, strings.ToLower(kind), ns, name)
+	if r.config.effectiveRules && strings.ToLower(kind) == kindServiceAccount {
+		r.newEffectiveRulesNode(gns, node, KindNamespacedName{kind, NamespacedName{ns, name}})
+	}
+	return node
+}
+
+// muteDefaultServiceAccount returns true if the ServiceAccount is a "default" one, which should be muted when using
+// -hide-default-sa, unless it was explicitly selected. It's hidden altogether unless it has non-default bindings (see
+// hideDefaultServiceAccount).
+func (r *Rback) muteDefaultServiceAccount(name string) bool {
+	explicitlySelected := r.config.resourceKind == kindServiceAccount && contains(r.config.resourceNames, name)
+	return r.config.hideDefaultSA && name == "default" && !explicitlySelected
+}
+
+// hideDefaultServiceAccount returns true if the ServiceAccount is a "default" one that should be muted, and is only
+// bound by the default bindings that Kubernetes creates (see isDefaultBinding), if at all
+func (r *Rback) hideDefaultServiceAccount(ns string, name string) bool {
+	if !r.muteDefaultServiceAccount(name) {
+		return false
+	}
+	for _, binding := range r.bindingsOf(KindNamespacedName{"ServiceAccount", NamespacedName{ns, name}}) {
+		if !isDefaultBinding(binding) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rback) subjectExists(kind string, ns string, name string) bool {
+	if strings.ToLower(kind) != kindServiceAccount {
+		return true // assume users and groups exist
+	}
+
+	if sas, nsExists := r.permissions.ServiceAccounts[ns]; nsExists {
+		if _, saExists := sas[name]; saExists {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rback) isFocused(kind string, ns string, name string) bool {
+	if kind == kindRule {
+		return r.ruleMatchesSelection(NamespacedName{ns, name})
+	} else {
+		return r.config.resourceKind == kind && r.namespaceSelected(ns) && r.resourceNameSelected(name)
+	}
+}
+
+func (r *Rback) ruleMatchesSelection(roleRef NamespacedName) bool {
+	if r.config.resourceKind == kindRule {
+		if role, found := r.lookupRole(roleRef); found {
+			return r.config.whoCan.matchesAnyRuleIn(role)
+		}
+	}
+	return false
+}
+
+func (w *WhoCan) matchesAnyRuleIn(role Role) bool {
+	for _, rule := range role.rules {
+		if w.matches(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WhoCan) matches(rule Rule) bool {
+	return (contains(rule.verbs, "*") || contains(rule.verbs, w.verb)) &&
+		(contains(rule.resources, "*") || contains(rule.resources, w.resourceKind)) &&
+		rule.matchesAPIGroup(w.apiGroup) &&
+		(w.resourceName == "" || len(rule.resourceNames) == 0 || contains(rule.resourceNames, w.resourceName))
+}
+
+// splitGroupResource splits a resource qualified with its API group like kubectl accepts it (e.g.
+// "certificates.cert-manager.io") into the resource and the group, which is "" if the resource isn't qualified. Like
+// kubectl, it accepts singular resources and short names, which are turned into the plural that rules use.
+func splitGroupResource(s string) (resource, apiGroup string) {
+	parts := strings.SplitN(s, ".", 2)
+	if len(parts) == 1 {
+		return pluralResource(s), ""
+	}
+	return pluralResource(parts[0]), parts[1]
+}
+
+// matchesAPIGroup returns true if the rule applies to the given API group; an empty group (i.e. a resource that
+// wasn't qualified with its group) matches all groups
+func (r *Rule) matchesAPIGroup(apiGroup string) bool {
+	return apiGroup == "" || contains(r.apiGroups, "*") || contains(r.apiGroups, apiGroup)
+}
+
+// newRulesNode renders the rules of the given role. The rules of a ClusterRole bound by a RoleBinding only apply in the
+// binding's namespace, so they get their own node in that namespace. Each rules node is only rendered once, however many
+// bindings reach it, and returned as is after that.
+func (r *Rback) newRulesNode(g *dot.Graph, bindingNamespace string, roleRef NamespacedName, highlight bool) *dot.Node {
+	id := r.rulesNodeID(bindingNamespace, roleRef)
+	if node, rendered := r.rulesNodes[id]; rendered {
+		return node
+	}
+	node := r.renderRulesNode(g, bindingNamespace, roleRef, highlight)
+	r.rulesNodes[id] = node
+	return node
+}
+
+func (r *Rback) renderRulesNode(g *dot.Graph, bindingNamespace string, roleRef NamespacedName, highlight bool) *dot.Node {
+	var rulesText string
+	var plainLines []string // the rules as plain text, for formats other than dot
+	table := r.config.rulesStyle == rulesStyleTable
+	formatRule := ruleLine
+	ellipsis := regularLine("...")
+	if table {
+		formatRule = ruleRow
+		ellipsis = rulesTableSpanningRow(escapeHTML("..."))
+	}
+	if role, found := r.lookupRole(roleRef); found {
+		rules := r.shownRules(role.rules)
+		if r.config.rulesFormat == rulesFormatCompact {
+			rules = compactRules(rules)
+		}
+		for _, rule := range rules {
+			if rule.rendersEmpty() {
+				continue // it would only add a blank line, or a blank box if the role has no other rules
+			}
+			ruleMatches := r.config.resourceKind == kindRule && highlight && r.config.whoCan.matches(rule)
+			highlightScoped := r.config.highlightScopedRules && len(rule.resourceNames) > 0
+			if ruleMatches {
+				rulesText += formatRule(rule, true, highlightScoped)
+				plainLines = append(plainLines, rule.toHumanReadableString())
+			} else {
+				if r.config.whoCan.showMatchedOnly {
+					if !strings.HasSuffix(rulesText, ellipsis) {
+						rulesText += ellipsis
+						plainLines = append(plainLines, "...")
+					}
+				} else {
+					rulesText += formatRule(rule, false, highlightScoped)
+					plainLines = append(plainLines, rule.toHumanReadableString())
+				}
+			}
+		}
+	} else {
+		// rather than an empty box, make clear that the rules are unknown (not that there are none)
+		unavailable := "rules unavailable (role not found)"
+		if r.permissions.IgnoredRoles[roleRef.namespace][roleRef.name] {
+			unavailable = "rules unavailable (role ignored via -ignore-prefixes)"
+		}
+		rulesText = iff(table, rulesTableSpanningRow("<i>"+escapeHTML(unavailable)+"</i>"), italicLine(unavailable))
+		plainLines = append(plainLines, unavailable)
+	}
+	if rulesText == "" {
+		return nil
+	} else {
+		if r.config.annotateRulesScope && roleRef.namespace == "" && bindingNamespace != "" {
+			// the rules of a ClusterRole bound by a RoleBinding only apply in the binding's namespace
+			scope := fmt.Sprintf("(scoped to %s)", bindingNamespace)
+			rulesText = iff(table, rulesTableSpanningRow("<i>"+escapeHTML(scope)+"</i>"), italicLine(scope)) + rulesText
+			plainLines = append([]string{scope}, plainLines...)
+		}
+		if table {
+			rulesText = rulesTable(rulesText)
+		}
+		r.model.addNode(r.rulesNodeID(bindingNamespace, roleRef), "Rules", iff(roleRef.namespace == "", bindingNamespace, roleRef.namespace), strings.Join(plainLines, "\n"))
+		var node dot.Node
+		if roleRef.namespace == "" {
+			clusterWide := bindingNamespace == "" && (!r.config.mergeRules || r.boundClusterWide(roleRef))
+			node = newRulesNode0(g, kindClusterRole, bindingNamespace, roleRef.name, rulesText, clusterWide, highlight)
+		} else {
+			node = newRulesNode0(g, kindRole, roleRef.namespace, roleRef.name, rulesText, false, highlight)
+		}
+		return &node
+	}
+}
+
+// rendersEmpty returns true for rules that grant nothing that could be rendered, e.g. those only listing the core API
+// group without any verbs or resources
+func (r *Rule) rendersEmpty() bool {
+	return strings.TrimSpace(r.toHumanReadableString()) == ""
+}
+
+func (r *Rule) toHumanReadableString() string {
+	result := strings.Join(r.verbs, ",")
+	if len(r.resources) > 0 {
+		result += fmt.Sprintf(` %v`, strings.Join(r.resources, ","))
+	}
+	if len(r.resourceNames) > 0 {
+		result += fmt.Sprintf(` "%v"`, strings.Join(r.resourceNames, ","))
+	}
+	if len(r.nonResourceURLs) > 0 {
+		result += fmt.Sprintf(` %v`, strings.Join(r.nonResourceURLs, ","))
+	}
+	if len(r.apiGroups) > 1 || (len(r.apiGroups) == 1 && r.apiGroups[0] != "") {
+		// name the core group explicitly, so that resources with the same name in different groups can be told apart
+		apiGroups := []string{}
+		for _, group := range r.apiGroups {
+			apiGroups = append(apiGroups, iff(group == "", "core", group))
+		}
+		result += fmt.Sprintf(` (%v)`, strings.Join(apiGroups, ","))
+	}
+	return result
+}
+
+func (r *Rback) resourceNameSelected(name string) bool {
+	return r.allResourceNames() || contains(r.config.resourceNames, name)
+}
+
+// findMissingResourceNames returns the names given on the command line for which no resource of the selected kind
+// exists in the selected namespaces (Users and Groups only exist as subjects, so they're never missing)
+func (r *Rback) findMissingResourceNames() []string {
+	missing := []string{}
+	for _, name := range r.config.resourceNames {
+		found := false
+		switch r.config.resourceKind {
+		case kindServiceAccount:
+			for ns, sas := range r.permissions.ServiceAccounts {
+				_, exists := sas[name]
+				found = found || (exists && r.namespaceSelected(ns))
+			}
+		case kindRole, kindClusterRole:
+			for ns, roles := range r.permissions.Roles {
+				_, exists := roles[name]
+				found = found || (exists && (ns == "") == (r.config.resourceKind == kindClusterRole) && (ns == "" || r.namespaceSelected(ns)))
+			}
+		case kindRoleBinding, kindClusterRoleBinding:
+			for ns, bindings := range r.permissions.RoleBindings {
+				_, exists := bindings[name]
+				found = found || (exists && (ns == "") == (r.config.resourceKind == kindClusterRoleBinding) && (ns == "" || r.namespaceSelected(ns)))
+			}
+		default:
+			found = true
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func (r *Rback) allResourceNames() bool {
+	return len(r.config.resourceNames) == 0
+}
+
+func (r *Rback) namespaceSelected(ns string) bool {
+	return r.allNamespaces() || contains(r.config.namespaces, ns)
+}
+
+func (r *Rback) allNamespaces() bool {
+	return len(r.config.namespaces) == 1 && r.config.namespaces[0] == ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package rback
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// benchmarkRback returns an instance with the large input of benchmarkInput parsed
+func benchmarkRback(b *testing.B, args ...string) *Rback {
+	b.Helper()
+	r := &Rback{config: testConfig(b, append([]string{"-quiet"}, args...)...)}
+	if err := r.parseRBAC(bytes.NewReader(benchmarkInput(b))); err != nil {
+		b.Fatal(err)
+	}
+	return r
+}
+
+func BenchmarkGenGraph(b *testing.B) {
+	r := benchmarkRback(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = r.genGraph().String()
+	}
+}
+
+func BenchmarkReportSections(b *testing.B) {
+	r := benchmarkRback(b, "-report-only")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.reportSections()
+	}
+}
+
+func TestResourceNames(t *testing.T) {
+	tests := []struct {
+		args     []string
+		names    []string
+		missing  []string
+		rendered []string // the rendered ServiceAccounts, bindings and roles
+	}{
+		// b/default is bound through the system:serviceaccounts:b group
+		{[]string{"sa", "default,missing", "-n", "a"}, []string{"default", "missing"}, []string{"missing"},
+			[]string{"ServiceAccount a/default"}},
+		{[]string{"get", "sa", "default", "missing"}, []string{"default", "missing"}, []string{"missing"},
+			[]string{"ClusterRole admin-all", "ClusterRoleBinding admin-all-b", "ServiceAccount a/default", "ServiceAccount b/default"}},
+		{[]string{"serviceaccounts", "default,default"}, []string{"default", "default"}, []string{},
+			[]string{"ClusterRole admin-all", "ClusterRoleBinding admin-all-b", "ServiceAccount a/default", "ServiceAccount b/default"}},
+		{[]string{"crb", "admin-all-b", "pod-reader"}, []string{"admin-all-b", "pod-reader"}, []string{"pod-reader"},
+			[]string{"ClusterRole admin-all", "ClusterRoleBinding admin-all-b"}},
+		{[]string{"rb", "pod-reader", "-n", "b"}, []string{"pod-reader"}, []string{"pod-reader"}, []string{}},
+		// along with their bindings, and pod-reader also as bound in namespace a
+		{[]string{"cr", "admin-all,pod-reader"}, []string{"admin-all", "pod-reader"}, []string{},
+			[]string{"ClusterRole admin-all", "ClusterRole pod-reader", "ClusterRole pod-reader", "ClusterRoleBinding admin-all-b", "RoleBinding a/pod-reader"}},
+		{[]string{"role", "pod-reader"}, []string{"pod-reader"}, []string{"pod-reader"}, []string{}},
+	}
+	for _, test := range tests {
+		config := testConfig(t, append([]string{"-quiet", "-show-legend=false"}, test.args...)...)
+		if !equalStrings(config.resourceNames, test.names) {
+			t.Errorf("%v: resource names %q, expected %q", test.args, config.resourceNames, test.names)
+		}
+		r := testRback(t, config, "../../examples/serviceaccount-groups.json")
+		if missing := r.findMissingResourceNames(); !equalStrings(missing, test.missing) {
+			t.Errorf("%v: missing %q, expected %q", test.args, missing, test.missing)
+		}
+		r.genGraph()
+		rendered := []string{}
+		for _, node := range r.model.nodes {
+			switch node.kind {
+			case "ServiceAccount", "RoleBinding", "ClusterRoleBinding", "Role", "ClusterRole":
+				rendered = append(rendered, node.kind+" "+NamespacedName{node.namespace, node.label}.qualifiedName())
+			}
+		}
+		sort.Strings(rendered)
+		if !equalStrings(rendered, test.rendered) {
+			t.Errorf("%v: rendered %q, expected %q", test.args, rendered, test.rendered)
+		}
+	}
+}
+
+func TestClusterRoleBindingsOfServiceAccountsInSelectedNamespaces(t *testing.T) {
+	items := []string{
+		`{"kind": "ServiceAccount", "metadata": {"name": "app", "namespace": "a"}}`,
+		`{"kind": "ServiceAccount", "metadata": {"name": "app", "namespace": "b"}}`,
+		`{"kind": "ClusterRole", "metadata": {"name": "view"},
+		  "rules": [{"apiGroups": [""], "resources": ["pods"], "verbs": ["get"]}]}`,
+		`{"kind": "ClusterRoleBinding", "metadata": {"name": "view-a"}, "roleRef": {"kind": "ClusterRole", "name": "view"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "app", "namespace": "a"}]}`,
+		`{"kind": "ClusterRoleBinding", "metadata": {"name": "view-b"}, "roleRef": {"kind": "ClusterRole", "name": "view"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "app", "namespace": "b"}]}`,
+		`{"kind": "ClusterRoleBinding", "metadata": {"name": "view-both"}, "roleRef": {"kind": "ClusterRole", "name": "view"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "app", "namespace": "a"},
+		               {"kind": "ServiceAccount", "name": "app", "namespace": "b"}]}`,
+	}
+	tests := []struct {
+		namespaces string
+		expected   []string // the rendered ServiceAccounts and bindings, each once
+	}{
+		{"a", []string{"ClusterRoleBinding view-a", "ClusterRoleBinding view-both", "ServiceAccount a/app"}},
+		{"b", []string{"ClusterRoleBinding view-b", "ClusterRoleBinding view-both", "ServiceAccount b/app"}},
+		{"a,b", []string{"ClusterRoleBinding view-a", "ClusterRoleBinding view-b", "ClusterRoleBinding view-both",
+			"ServiceAccount a/app", "ServiceAccount b/app"}},
+		{"c", []string{}},
+	}
+	for _, test := range tests {
+		r := testRbackFromItems(t, testConfig(t, "-quiet", "-show-legend=false", "-n", test.namespaces), items...)
+		for _, name := range []string{"view-a", "view-b"} {
+			binding := r.permissions.RoleBindings[""][name]
+			ns := strings.TrimPrefix(name, "view-")
+			if selected := r.bindsServiceAccountInSelectedNamespace(binding); selected != strings.Contains(test.namespaces, ns) {
+				t.Errorf("-n %s: ClusterRoleBinding %s binds a ServiceAccount in a selected namespace: %v", test.namespaces, name, selected)
+			}
+		}
+
+		dot := r.genGraph().String()
+		rendered := []string{}
+		for _, node := range r.model.nodes {
+			switch node.kind {
+			case "ServiceAccount", "ClusterRoleBinding":
+				rendered = append(rendered, node.kind+" "+NamespacedName{node.namespace, node.label}.qualifiedName())
+			}
+		}
+		sort.Strings(rendered)
+		if !equalStrings(rendered, test.expected) {
+			t.Errorf("-n %s: rendered %q, expected %q", test.namespaces, rendered, test.expected)
+		}
+		// the subjects of view-both must each be connected to it once, even though both namespaces are rendered
+		if count := strings.Count(dot, "->"); count != len(r.model.edges) {
+			t.Errorf("-n %s: %d edges declared, expected %d:\n%s", test.namespaces, count, len(r.model.edges), dot)
+		}
+	}
+}
+
+func TestHideDefaultServiceAccounts(t *testing.T) {
+	sa := func(ns string) string {
+		return `{"kind": "ServiceAccount", "metadata": {"name": "default", "namespace": "` + ns + `"}}`
+	}
+	r := testRbackFromItems(t, testConfig(t, "-quiet", "-show-legend=false", "-ignore-prefixes", "none", "-hide-default-sa"),
+		sa("unbound"), sa("default-bound"), sa("bound"),
+		`{"kind": "ClusterRole", "metadata": {"name": "system:auth-delegator"}}`,
+		`{"kind": "ClusterRoleBinding", "metadata": {"name": "system:auth-delegator"},
+		  "roleRef": {"kind": "ClusterRole", "name": "system:auth-delegator"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "default", "namespace": "default-bound"}]}`,
+		`{"kind": "Role", "metadata": {"name": "reader", "namespace": "bound"}}`,
+		`{"kind": "RoleBinding", "metadata": {"name": "reader", "namespace": "bound"},
+		  "roleRef": {"kind": "Role", "name": "reader"},
+		  "subjects": [{"kind": "ServiceAccount", "name": "default", "namespace": "bound"}]}`)
+	dot := r.genGraph().String()
+
+	for _, ns := range []string{"unbound", "default-bound"} {
+		if nodes := modelNodes(r, "ServiceAccount", ns); len(nodes) != 0 {
+			t.Errorf("expected the default ServiceAccount in namespace %s to be hidden, got %v", ns, nodes)
+		}
+	}
+	if nodes := modelNodes(r, "ServiceAccount", "bound"); len(nodes) != 1 {
+		t.Errorf("expected the default ServiceAccount in namespace bound to be rendered, got %v", nodes)
+	}
+	if !strings.Contains(dot, `fontcolor="#b0b0b0"`) {
+		t.Errorf("expected the default ServiceAccount in namespace bound to be muted, got:\n%s", dot)
+	}
+}
+
+func TestGraphIsTheSameEachTime(t *testing.T) {
+	items := []string{}
+	for _, ns := range []string{"a", "b", "c", "d", "e"} {
+		items = append(items,
+			`{"kind": "ServiceAccount", "metadata": {"name": "unbound", "namespace": "`+ns+`"}}`,
+			`{"kind": "Role", "metadata": {"name": "unused", "namespace": "`+ns+`"}}`,
+			`{"kind": "Role", "metadata": {"name": "reader", "namespace": "`+ns+`"}}`,
+			`{"kind": "RoleBinding", "metadata": {"name": "reader", "namespace": "`+ns+`"},
+			  "roleRef": {"kind": "Role", "name": "reader"},
+			  "subjects": [{"kind": "ServiceAccount", "name": "worker", "namespace": "`+ns+`"}]}`,
+			`{"kind": "ClusterRoleBinding", "metadata": {"name": "view-`+ns+`"},
+			  "roleRef": {"kind": "ClusterRole", "name": "view"},
+			  "subjects": [{"kind": "ServiceAccount", "name": "worker", "namespace": "`+ns+`"}]}`)
+	}
+	r := testRbackFromItems(t, testConfig(t, "-quiet", "-overview"), items...)
+	expected := r.genGraph().String()
+	for i := 0; i < 10; i++ {
+		if actual := r.genGraph().String(); actual != expected {
+			t.Fatalf("expected the same graph each time, got:\n%s\nand:\n%s", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package rback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// severities of the findings of the analysis passes, from lowest to highest, for -fail-on
+const (
+	severityLow    = "low"
+	severityMedium = "medium"
+	severityHigh   = "high"
+)
+
+var severities = []string{severityLow, severityMedium, severityHigh}
+
+// severityRank returns the position of the severity in severities, i.e. a higher rank is more severe
+func severityRank(severity string) int {
+	for i, s := range severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// the codes of the kinds of findings, one per report section. They're stable, so that findings can be tracked (and
+// suppressed via -suppress) across versions: new kinds get new codes, and codes are never reused.
+const (
+	codeWildcard         = "RBACK001" // all verbs or all resources
+	codeSecretReaders    = "RBACK002"
+	codeEscalation       = "RBACK003"
+	codeCrossNamespace   = "RBACK004"
+	codeMissingWellKnown = "RBACK005"
+	codeMissingRole      = "RBACK006"
+	codeOrphanSA         = "RBACK007"
+	codePathClusterAdmin = "RBACK008"
+	codeSecretWriters    = "RBACK009"
+	codeImpersonation    = "RBACK010"
+	codePublicAccess     = "RBACK011" // system:authenticated, system:unauthenticated or system:anonymous
+)
+
+var findingCodes = []string{codeWildcard, codeSecretReaders, codeEscalation, codeCrossNamespace, codeMissingWellKnown,
+	codeMissingRole, codeOrphanSA, codePathClusterAdmin, codeSecretWriters, codeImpersonation,
+	codePublicAccess}
+
+// reportSection holds the findings of an analysis pass (or a part of one), all with the same code and severity. The
+// findings are kept both as human-readable lines and as items for further processing.
+type reportSection struct {
+	Title    string          `json:"title"`
+	Code     string          `json:"code"`
+	Severity string          `json:"severity"`
+	Findings []string        `json:"findings"`
+	Items    []reportFinding `json:"items"`
+}
+
+// reportFinding is a finding in machine-parseable form, e.g. for security dashboards
+type reportFinding struct {
+	Code      string `json:"code"`
+	Severity  string `json:"severity"`
+	Subject   string `json:"subject"`             // the subject (or binding) the finding is about, e.g. "ServiceAccount ns/name"
+	Namespace string `json:"namespace,omitempty"` // where the finding applies, i.e. "" for cluster-wide grants
+	Detail    string `json:"detail"`              // the same as the human-readable finding
+}
+
+func newReportSection(code, title, severity string, findings []finding) reportSection {
+	section := reportSection{Title: title, Code: code, Severity: severity, Findings: []string{}, Items: []reportFinding{}}
+	for _, f := range findings {
+		section.add(f.subject.kind+" "+f.subject.qualifiedName(), f.binding.namespace, f.String())
+	}
+	return section
+}
+
+// emptyReportSection returns a section without any findings yet, which are added via add
+func emptyReportSection(code, title, severity string) reportSection {
+	return newReportSection(code, title, severity, nil)
+}
+
+func (s *reportSection) add(subject, namespace, detail string) {
+	s.Findings = append(s.Findings, detail)
+	s.Items = append(s.Items, reportFinding{s.Code, s.Severity, subject, namespace, detail})
+}
+
+// reportSections runs the analysis passes enabled via the -report-* flags. With -report-only or -format markdown and no
+// such flag, all passes are run.
+func (r *Rback) reportSections() []reportSection {
+	all := (r.config.reportOnly || r.config.format == formatMarkdown) && !r.config.reportWildcards && !r.config.reportSecretReaders &&
+		!r.config.reportSecretWriters && !r.config.reportEscalation && !r.config.reportImpersonation && !r.config.reportCrossNamespace &&
+		!r.config.reportOrphans && !r.config.reportPublicAccess
+	return r.runAnalysisPasses(func(code string) bool {
+		switch code {
+		case codeWildcard:
+			return all || r.config.reportWildcards
+		case codeSecretReaders:
+			return all || r.config.reportSecretReaders
+		case codeSecretWriters:
+			return all || r.config.reportSecretWriters
+		case codeEscalation:
+			return all || r.config.reportEscalation
+		case codeImpersonation:
+			return all || r.config.reportImpersonation
+		case codePublicAccess:
+			return all || r.config.reportPublicAccess
+		case codeCrossNamespace:
+			return all || r.config.reportCrossNamespace
+		case codeMissingWellKnown, codeMissingRole, codeOrphanSA:
+			return all || r.config.reportOrphans
+		}
+		return true // the paths to cluster-admin, which are only found with -path-to
+	})
+}
+
+// runAnalysisPasses runs the analysis passes of the selected codes. The sections of the codes given via -suppress are
+// left out.
+func (r *Rback) runAnalysisPasses(selected func(code string) bool) []reportSection {
+	run := func(code string) bool {
+		return selected(code) && !contains(r.config.suppressedCodes, code)
+	}
+	sections := []reportSection{}
+	if run(codeWildcard) {
+		sections = append(sections, newReportSection(codeWildcard, "Subjects granted all verbs or all resources", severityHigh, r.findWildcardGrants()))
+	}
+	if run(codeSecretReaders) {
+		sections = append(sections, newReportSection(codeSecretReaders, "Subjects that can read all secrets", severityHigh, r.findSecretReaders()))
+	}
+	if run(codeSecretWriters) {
+		sections = append(sections, newReportSection(codeSecretWriters, "Subjects that can write secrets", severityHigh, r.findSecretWriters()))
+	}
+	if run(codeEscalation) {
+		sections = append(sections, newReportSection(codeEscalation, "Subjects that can escalate their privileges by writing RBAC resources", severityHigh, r.findEscalationGrants()))
+	}
+	if run(codeImpersonation) {
+		sections = append(sections, newReportSection(codeImpersonation, "Subjects that can impersonate other identities", severityHigh, r.findImpersonationGrants()))
+	}
+	if run(codePublicAccess) {
+		sections = append(sections, newReportSection(codePublicAccess, "Permissions granted to all authenticated or unauthenticated users", severityHigh, r.findPublicAccessGrants()))
+	}
+	if run(codeCrossNamespace) {
+		sections = append(sections, newReportSection(codeCrossNamespace, "ServiceAccounts with permissions outside of their namespace", severityMedium, r.findCrossNamespaceGrants()))
+	}
+	sections = append(sections, r.orphansReportSections(run)...)
+	if r.config.pathTo != "" && run(codePathClusterAdmin) {
+		sections = append(sections, r.adminPathReportSection())
+	}
+	return sections
+}
+
+// orphansReportSections lists the bindings that reference missing roles (those referencing well-known ClusterRoles
+// separately, since they're more likely broken by accident) and the ServiceAccounts that aren't bound to any role, as
+// far as their codes are selected
+func (r *Rback) orphansReportSections(selected func(code string) bool) []reportSection {
+	if !selected(codeMissingWellKnown) && !selected(codeMissingRole) && !selected(codeOrphanSA) {
+		return nil
+	}
+	wellKnown, others := r.findDanglingBindings()
+	wellKnownSection := emptyReportSection(codeMissingWellKnown, "Bindings referencing missing well-known ClusterRoles", severityMedium)
+	for _, d := range wellKnown {
+		wellKnownSection.add(d.bindingDescription(), d.binding.namespace, d.String()+" (well-known role missing)")
+	}
+	othersSection := emptyReportSection(codeMissingRole, "Bindings referencing other missing roles", severityLow)
+	for _, d := range others {
+		othersSection.add(d.bindingDescription(), d.binding.namespace, d.String())
+	}
+	orphansSection := emptyReportSection(codeOrphanSA, "ServiceAccounts not bound to any role", severityLow)
+	for _, sa := range r.findOrphanServiceAccounts() {
+		orphansSection.add("ServiceAccount "+sa.qualifiedName(), sa.namespace, "ServiceAccount "+sa.qualifiedName())
+	}
+	sections := []reportSection{}
+	for _, section := range []reportSection{wellKnownSection, othersSection, orphansSection} {
+		if selected(section.Code) {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// writeTextReport writes the report sections as plain text
+func writeTextReport(w io.Writer, sections []reportSection) {
+	for _, section := range sections {
+		fmt.Fprintf(w, "%s [%s]: %d found\n", section.Title, section.Code, len(section.Findings))
+		for _, f := range section.Findings {
+			fmt.Fprintf(w, "  %s\n", f)
+		}
+	}
+}
+
+// writeJSONReport writes the report sections as JSON (see schema/report.v1.json), for further processing in pipelines
+func writeJSONReport(w io.Writer, sections []reportSection) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		SchemaVersion int             `json:"schemaVersion"`
+		Sections      []reportSection `json:"sections"`
+	}{JSONSchemaVersion, sections})
+}
+
+// findingsAtOrAbove returns the number of findings with at least the given severity
+func findingsAtOrAbove(sections []reportSection, severity string) int {
+	count := 0
+	for _, section := range sections {
+		if severityRank(section.Severity) >= severityRank(severity) {
+			count += len(section.Findings)
+		}
+	}
+	return count
+}
@@ -0,0 +1,59 @@
+package rback
+
+import "strings"
+
+// resourceAliases maps the short names and irregular singulars of common resources, as accepted by kubectl, to the
+// plural names that access rules use
+var resourceAliases = map[string]string{
+	"cm":        "configmaps",
+	"cj":        "cronjobs",
+	"crd":       "customresourcedefinitions",
+	"csr":       "certificatesigningrequests",
+	"deploy":    "deployments",
+	"ds":        "daemonsets",
+	"endpoints": "endpoints",
+	"ep":        "endpoints",
+	"ev":        "events",
+	"hpa":       "horizontalpodautoscalers",
+	"ing":       "ingresses",
+	"limits":    "limitranges",
+	"netpol":    "networkpolicies",
+	"no":        "nodes",
+	"ns":        "namespaces",
+	"pdb":       "poddisruptionbudgets",
+	"po":        "pods",
+	"psp":       "podsecuritypolicies",
+	"pv":        "persistentvolumes",
+	"pvc":       "persistentvolumeclaims",
+	"quota":     "resourcequotas",
+	"rc":        "replicationcontrollers",
+	"rs":        "replicasets",
+	"sa":        "serviceaccounts",
+	"sc":        "storageclasses",
+	"sts":       "statefulsets",
+	"svc":       "services",
+}
+
+// pluralResource returns the plural name of a resource as given by the user, so that e.g. "pod", "po" and "pods" all
+// match the rules for pods. Subresources (e.g. "pod/log") keep their suffix, and "*" is returned as is.
+func pluralResource(resource string) string {
+	resource = strings.ToLower(resource)
+	if parts := strings.SplitN(resource, "/", 2); len(parts) == 2 {
+		return pluralResource(parts[0]) + "/" + parts[1]
+	}
+	if plural, ok := resourceAliases[resource]; ok {
+		return plural
+	}
+	switch {
+	case resource == "*" || resource == "":
+		return resource
+	case strings.HasSuffix(resource, "ss") || strings.HasSuffix(resource, "ch") || strings.HasSuffix(resource, "sh") ||
+		strings.HasSuffix(resource, "x"):
+		return resource + "es" // e.g. ingress
+	case strings.HasSuffix(resource, "s"):
+		return resource // already plural
+	case len(resource) > 1 && strings.HasSuffix(resource, "y") && !strings.ContainsAny(resource[len(resource)-2:len(resource)-1], "aeiou"):
+		return resource[:len(resource)-1] + "ies" // e.g. networkpolicy
+	}
+	return resource + "s"
+}
@@ -0,0 +1,88 @@
+package rback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// the actions of a risk policy for the findings of a code, as enforced by -fail-on-risky
+const (
+	riskFatal  = "fatal"  // exit with a non-zero status
+	riskWarn   = "warn"   // only log the findings
+	riskIgnore = "ignore" // don't even look for them
+)
+
+var riskActions = []string{riskFatal, riskWarn, riskIgnore}
+
+// defaultRiskPolicy treats grants of wildcards, of writing secrets, of escalating privileges and of impersonation as
+// fatal. A -risk-policy file overrides the action of the codes it lists.
+var defaultRiskPolicy = map[string]string{
+	codeWildcard:      riskFatal,
+	codeSecretWriters: riskFatal,
+	codeEscalation:    riskFatal,
+	codeImpersonation: riskFatal,
+}
+
+// loadRiskPolicy reads a JSON object mapping finding codes to actions, e.g. {"RBACK002": "fatal", "RBACK010": "warn"},
+// on top of the defaultRiskPolicy
+func loadRiskPolicy(path string) (map[string]string, error) {
+	policy := map[string]string{}
+	for code, action := range defaultRiskPolicy {
+		policy[code] = action
+	}
+	if path == "" {
+		return policy, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	overrides := map[string]string{}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("expected a JSON object mapping finding codes to %s: %v", strings.Join(riskActions, ", "), err)
+	}
+	for code, action := range overrides {
+		code = strings.ToUpper(code)
+		if !contains(findingCodes, code) {
+			return nil, fmt.Errorf("unknown finding code %q, expected one of: %s", code, strings.Join(findingCodes, ", "))
+		}
+		if !contains(riskActions, action) {
+			return nil, fmt.Errorf("unknown action %q for %s, expected one of: %s", action, code, strings.Join(riskActions, ", "))
+		}
+		policy[code] = action
+	}
+	return policy, nil
+}
+
+// failOnRisky logs the findings of the codes that the risk policy treats as fatal or as warnings, and exits with a
+// non-zero status if there are any fatal ones. Codes given via -suppress are left out.
+func (r *Rback) failOnRisky() {
+	if r.config.riskPolicy == nil {
+		return
+	}
+	sections := r.runAnalysisPasses(func(code string) bool {
+		return r.config.riskPolicy[code] == riskFatal || r.config.riskPolicy[code] == riskWarn
+	})
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i].Code < sections[j].Code
+	})
+	fatal := 0
+	for _, section := range sections {
+		for _, f := range section.Findings {
+			if r.config.riskPolicy[section.Code] == riskFatal {
+				errorf("%s: %s", section.Code, f)
+				fatal++
+			} else {
+				warnf("%s: %s", section.Code, f)
+			}
+		}
+	}
+	if fatal > 0 {
+		errorf("Found %d risky grants that the risk policy treats as fatal", fatal)
+		os.Exit(-3)
+	}
+}
@@ -0,0 +1,88 @@
+package rback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// commandRoleUsage prints how many bindings reference each role and how many subjects they grant it to, surfacing
+// unused roles (candidates for cleanup) and heavily used ones (risky to change)
+const commandRoleUsage = "role-usage"
+
+// roleUsage is the usage of a Role or ClusterRole, as printed by the role-usage command (see
+// schema/role-usage.v1.json)
+type roleUsage struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Bindings  int    `json:"bindings"`
+	Subjects  int    `json:"subjects"` // distinct subjects across all of its bindings
+}
+
+// roleUsages counts the bindings referencing each role of the selected namespaces, and each ClusterRole, sorted by the
+// number of bindings, then of subjects, descending. Bindings of all namespaces count, including those hidden by
+// -ignore-prefixes, since a role they reference is still in use.
+func (r *Rback) roleUsages() []roleUsage {
+	bindings := map[NamespacedName]int{}
+	subjects := map[NamespacedName]map[KindNamespacedName]bool{}
+	for _, bindingsByNamespace := range []map[string]map[string]Binding{r.permissions.RoleBindings, r.permissions.IgnoredRoleBindings} {
+		for _, bs := range bindingsByNamespace {
+			for _, binding := range bs {
+				bindings[binding.role]++
+				if subjects[binding.role] == nil {
+					subjects[binding.role] = map[KindNamespacedName]bool{}
+				}
+				for _, subject := range binding.subjects {
+					subjects[binding.role][subject] = true
+				}
+			}
+		}
+	}
+
+	usages := []roleUsage{}
+	for ns, roles := range r.permissions.Roles {
+		if ns != "" && !r.namespaceSelected(ns) {
+			continue
+		}
+		for _, role := range roles {
+			usages = append(usages, roleUsage{iff(ns == "", "ClusterRole", "Role"), ns, role.name,
+				bindings[role.NamespacedName], len(subjects[role.NamespacedName])})
+		}
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+		if a.Bindings != b.Bindings {
+			return a.Bindings > b.Bindings
+		}
+		if a.Subjects != b.Subjects {
+			return a.Subjects > b.Subjects
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return NamespacedName{a.Namespace, a.Name}.qualifiedName() < NamespacedName{b.Namespace, b.Name}.qualifiedName()
+	})
+	return usages
+}
+
+// writeRoleUsage writes the usage of each role for the role-usage command, as a table or, with -format json, as JSON
+func (r *Rback) writeRoleUsage(w io.Writer) error {
+	usages := r.roleUsages()
+	if r.config.format == formatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			SchemaVersion int         `json:"schemaVersion"`
+			Roles         []roleUsage `json:"roles"`
+		}{JSONSchemaVersion, usages})
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tNAMESPACE\tNAME\tBINDINGS\tSUBJECTS")
+	for _, u := range usages {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", u.Kind, u.Namespace, u.Name, u.Bindings, u.Subjects)
+	}
+	return tw.Flush()
+}
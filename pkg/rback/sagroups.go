@@ -0,0 +1,54 @@
+package rback
+
+import (
+	"fmt"
+	"strings"
+)
+
+// serviceAccountsGroup is the virtual group that Kubernetes puts all ServiceAccounts into. Each ServiceAccount is also
+// in the group of its namespace, named serviceAccountsGroup + ":" + namespace.
+const serviceAccountsGroup = "system:serviceaccounts"
+
+// serviceAccountsGroupNamespace returns the namespace of the ServiceAccounts that a Group subject stands for, if it's
+// one of the virtual ServiceAccount groups (with "" for all namespaces)
+func serviceAccountsGroupNamespace(subject KindNamespacedName) (string, bool) {
+	if subject.kind != "Group" {
+		return "", false
+	}
+	if subject.name == serviceAccountsGroup {
+		return "", true
+	}
+	if strings.HasPrefix(subject.name, serviceAccountsGroup+":") {
+		return strings.TrimPrefix(subject.name, serviceAccountsGroup+":"), true
+	}
+	return "", false
+}
+
+// serviceAccountsGroupLabel spells out which ServiceAccounts a virtual ServiceAccount group binds, since binding one is
+// easily mistaken for binding a single identity
+func serviceAccountsGroupLabel(ns string) string {
+	if ns == "" {
+		return fmt.Sprintf("all ServiceAccounts\n(Group %s)", serviceAccountsGroup)
+	}
+	return fmt.Sprintf("all ServiceAccounts in %s\n(Group %s:%s)", ns, serviceAccountsGroup, ns)
+}
+
+// groupContainsSelectedServiceAccount returns true if the subject is a virtual ServiceAccount group that contains any
+// of the ServiceAccounts selected via the resource names and namespaces
+func (r *Rback) groupContainsSelectedServiceAccount(subject KindNamespacedName) bool {
+	groupNs, ok := serviceAccountsGroupNamespace(subject)
+	if !ok {
+		return false
+	}
+	for ns, sas := range r.permissions.ServiceAccounts {
+		if (groupNs != "" && ns != groupNs) || !r.namespaceSelected(ns) {
+			continue
+		}
+		for name := range sas {
+			if r.resourceNameSelected(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package rback
+
+import (
+	"sort"
+
+	"github.com/emicklei/dot"
+)
+
+// renderServiceAccountSecrets draws the Secrets that each rendered ServiceAccount references (its token Secrets and
+// imagePullSecrets), for -show-sa-secrets. The Secrets themselves aren't read, so they're assumed to exist.
+func (r *Rback) renderServiceAccountSecrets(g *dot.Graph) {
+	namespaces := []string{}
+	for ns := range r.permissions.ServiceAccounts {
+		if r.namespaceSelected(ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		names := []string{}
+		for name := range r.permissions.ServiceAccounts[ns] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			sa := r.permissions.ServiceAccounts[ns][name]
+			saID := subjectNodeID("ServiceAccount", ns, name)
+			if !r.model.nodeIDs[saID] || len(sa.secrets)+len(sa.imagePullSecrets) == 0 {
+				continue
+			}
+			gns := r.newNamespaceSubgraph(g, ns)
+			saNode := r.newSubjectNode(gns, "ServiceAccount", ns, name)
+			for _, secret := range sa.secrets {
+				r.newSecretNode(gns, saNode, saID, ns, secret, false)
+			}
+			for _, secret := range sa.imagePullSecrets {
+				r.newSecretNode(gns, saNode, saID, ns, secret, true)
+			}
+		}
+	}
+}
+
+func (r *Rback) newSecretNode(gns *dot.Graph, saNode dot.Node, saID, ns, name string, imagePull bool) {
+	id := secretNodeID(ns, name)
+	newServiceAccountToSecretEdge(saNode, newSecretNode0(gns, id, name), imagePull)
+	r.model.addNode(id, "Secret", ns, name)
+	r.model.addEdge(saID, id)
+}
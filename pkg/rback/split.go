@@ -0,0 +1,127 @@
+package rback
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const splitByNamespace = "namespace"
+
+var splitByValues = []string{splitByNamespace}
+
+// fileExtensions maps the output formats to the extensions of the files written by -split-by
+var fileExtensions = map[string]string{
+	formatDot:      ".dot",
+	formatMetrics:  ".prom",
+	formatGraphML:  ".graphml",
+	formatYAML:     ".yaml",
+	formatHTML:     ".html",
+	formatJSON:     ".json",
+	formatMarkdown: ".md",
+}
+
+// clusterWideFile is the name of the file written by -split-by for the cluster-wide objects. Namespace names can't start
+// with an underscore, so it can't collide with the file of a namespace.
+const clusterWideFile = "_cluster-wide"
+
+// writeSplit writes one file per selected namespace into dir, each rendered as if only that namespace was selected via
+// -n (so ClusterRoleBindings of its ServiceAccounts appear in each of their files), plus an index.html linking them.
+// Unless the rendering is limited to a kind of resource, all ClusterRoleBindings (including those of Users and Groups,
+// which aren't in any namespace) and the unbound ClusterRoles go into a file of their own.
+func (r *Rback) writeSplit(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	write := func(name string, config Config) error {
+		path := filepath.Join(dir, name+fileExtensions[r.config.format])
+		debugf("Writing %s to %s", name, path)
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		rendering := Rback{config: config, permissions: r.permissions}
+		err = rendering.writeOutput(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		return nil
+	}
+
+	clusterWide := r.config.resourceKind == "" && (len(r.permissions.RoleBindings[""]) > 0 || len(r.permissions.Roles[""]) > 0)
+	if clusterWide {
+		config := r.config
+		config.clusterWideOnly = true
+		if err := write(clusterWideFile, config); err != nil {
+			return err
+		}
+	}
+	namespaces := r.renderedNamespaces()
+	for _, ns := range namespaces {
+		config := r.config
+		config.namespaces = []string{ns}
+		if err := write(ns, config); err != nil {
+			return err
+		}
+	}
+
+	index, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+	return splitIndexTemplate.Execute(index, struct {
+		Extension   string
+		ClusterWide string
+		Namespaces  []string
+	}{fileExtensions[r.config.format], iff(clusterWide, clusterWideFile, ""), namespaces})
+}
+
+// renderedNamespaces returns the (sorted) namespaces of all parsed resources that are selected via -n
+func (r *Rback) renderedNamespaces() []string {
+	seen := map[string]bool{}
+	namespaces := []string{}
+	add := func(ns string) {
+		if ns != "" && !seen[ns] && r.namespaceSelected(ns) {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	for ns := range r.permissions.ServiceAccounts {
+		add(ns)
+	}
+	for ns := range r.permissions.Roles {
+		add(ns)
+	}
+	for ns := range r.permissions.RoleBindings {
+		add(ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+var splitIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>rback</title>
+</head>
+<body>
+{{- if .ClusterWide}}
+<p><a href="{{.ClusterWide}}{{.Extension}}">Cluster-wide bindings and roles</a></p>
+{{- end}}
+<h1>Namespaces</h1>
+<ul>
+{{- range .Namespaces}}
+<li><a href="{{.}}{{$.Extension}}">{{.}}</a></li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
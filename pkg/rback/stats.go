@@ -0,0 +1,88 @@
+package rback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// namespaceStats is the RBAC footprint of a namespace, as written by -stats (see schema/stats.v1.json)
+type namespaceStats struct {
+	Namespace                      string `json:"namespace"`
+	ServiceAccounts                int    `json:"serviceAccounts"`
+	Roles                          int    `json:"roles"`
+	RoleBindings                   int    `json:"roleBindings"`
+	ServiceAccountsWithWriteAccess int    `json:"serviceAccountsWithWriteAccess"` // granted any verb but -read-only-verbs
+	RoleBindingsToClusterRoles     int    `json:"roleBindingsToClusterRoles"`
+}
+
+// namespaceStats aggregates the parsed resources by namespace, for the selected namespaces
+func (r *Rback) namespaceStats() []namespaceStats {
+	stats := map[string]*namespaceStats{}
+	get := func(ns string) *namespaceStats {
+		if stats[ns] == nil {
+			stats[ns] = &namespaceStats{Namespace: ns}
+		}
+		return stats[ns]
+	}
+	for ns, sas := range r.permissions.ServiceAccounts {
+		if r.namespaceSelected(ns) {
+			get(ns).ServiceAccounts = len(sas)
+		}
+	}
+	for ns, roles := range r.permissions.Roles {
+		if ns != "" && r.namespaceSelected(ns) {
+			get(ns).Roles = len(roles)
+		}
+	}
+	for ns, bindings := range r.permissions.RoleBindings {
+		if ns == "" || !r.namespaceSelected(ns) {
+			continue
+		}
+		get(ns).RoleBindings = len(bindings)
+		for _, binding := range bindings {
+			if binding.role.namespace == "" {
+				get(ns).RoleBindingsToClusterRoles++
+			}
+		}
+	}
+	writers := map[NamespacedName]bool{}
+	r.eachGrant(func(binding Binding, subject KindNamespacedName, rule Rule) {
+		if subject.kind == "ServiceAccount" && r.namespaceSelected(subject.namespace) && !writers[subject.NamespacedName] &&
+			!rule.onlyGrants(r.config.readOnlyVerbs) {
+			writers[subject.NamespacedName] = true
+			get(subject.namespace).ServiceAccountsWithWriteAccess++
+		}
+	})
+
+	sorted := []namespaceStats{}
+	for _, s := range stats {
+		sorted = append(sorted, *s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Namespace < sorted[j].Namespace
+	})
+	return sorted
+}
+
+// writeStats writes the statistics of each namespace for -stats, as a table or, with -format json, as JSON
+func (r *Rback) writeStats(w io.Writer) error {
+	stats := r.namespaceStats()
+	if r.config.format == formatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			SchemaVersion int              `json:"schemaVersion"`
+			Namespaces    []namespaceStats `json:"namespaces"`
+		}{JSONSchemaVersion, stats})
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tSERVICEACCOUNTS\tROLES\tROLEBINDINGS\tSA WITH WRITE ACCESS\tBINDINGS TO CLUSTERROLES")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n", s.Namespace, s.ServiceAccounts, s.Roles, s.RoleBindings,
+			s.ServiceAccountsWithWriteAccess, s.RoleBindingsToClusterRoles)
+	}
+	return tw.Flush()
+}
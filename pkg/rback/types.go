@@ -0,0 +1,91 @@
+package rback
+
+import "time"
+
+type Permissions struct {
+	ServiceAccounts map[string]map[string]ServiceAccount
+	Roles           map[string]map[string]Role    // ClusterRoles are stored in Roles[""]
+	RoleBindings    map[string]map[string]Binding // ClusterRoleBindings are stored in RoleBindings[""]
+
+	// bindings matching -ignore-prefixes aren't rendered, but are needed to tell whether a subject is bound at all
+	IgnoredRoleBindings map[string]map[string]Binding
+	// likewise, roles matching -ignore-prefixes are needed to tell whether a role referenced by a binding exists at all
+	IgnoredRoles map[string]map[string]bool
+
+	// the labels of the Namespaces in the input, which are only needed for -group-by-label
+	NamespaceLabels map[string]map[string]string
+
+	Objects []map[string]interface{} // all parsed (non-ignored) resources as they were read, in input order
+}
+
+// counts returns the number of ServiceAccounts, (Cluster)Roles and (Cluster)RoleBindings parsed so far
+func (p *Permissions) counts() (serviceAccounts, roles, bindings int) {
+	for _, byName := range p.ServiceAccounts {
+		serviceAccounts += len(byName)
+	}
+	for _, byName := range p.Roles {
+		roles += len(byName)
+	}
+	for _, byName := range p.RoleBindings {
+		bindings += len(byName)
+	}
+	return serviceAccounts, roles, bindings
+}
+
+type ServiceAccount struct {
+	NamespacedName
+	json             string
+	automountToken   bool     // false if the ServiceAccount opts out of automounting its token via automountServiceAccountToken
+	secrets          []string // the names of the Secrets holding its tokens
+	imagePullSecrets []string // the names of the Secrets used to pull the images of its pods
+	colors           nodeColors
+	annotations      map[string]string
+}
+
+type Binding struct {
+	NamespacedName
+	role        NamespacedName
+	subjects    []KindNamespacedName
+	created     time.Time
+	colors      nodeColors
+	annotations map[string]string
+}
+
+type Role struct {
+	NamespacedName
+	rules       []Rule
+	created     time.Time
+	colors      nodeColors
+	annotations map[string]string
+}
+
+// nodeColors override the default colors of an object's node, as set through its annotations ("" keeps the default)
+type nodeColors struct {
+	fill, font string
+}
+
+type NamespacedName struct {
+	namespace string
+	name      string
+}
+
+type KindNamespacedName struct {
+	kind string
+	NamespacedName
+}
+
+type Rule struct {
+	verbs           []string
+	resources       []string
+	resourceNames   []string
+	nonResourceURLs []string
+	apiGroups       []string
+}
+
+// qualifiedName returns "namespace/name", or just the name for cluster-scoped resources
+func (nn NamespacedName) qualifiedName() string {
+	if nn.namespace == "" {
+		return nn.name
+	}
+	return nn.namespace + "/" + nn.name
+}
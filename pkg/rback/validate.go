@@ -0,0 +1,323 @@
+package rback
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// validateDOT checks that the generated graph is syntactically valid DOT (including the HTML-like labels, which
+// Graphviz parses as XML), to guard the escaping of labels against unusual characters in the input. It's used by the
+// hidden -validate flag.
+func validateDOT(src string) error {
+	tokens, err := tokenizeDOT(src)
+	if err != nil {
+		return err
+	}
+	p := dotParser{tokens: tokens}
+	return p.parseGraph()
+}
+
+type dotTokenKind int
+
+const (
+	dotID dotTokenKind = iota
+	dotHTML
+	dotPunct // one of { } [ ] = ; , : or an edge operator
+	dotEOF
+)
+
+type dotToken struct {
+	kind   dotTokenKind
+	value  string
+	line   int
+	offset int // of the token's first byte in the source
+}
+
+func tokenizeDOT(src string) ([]dotToken, error) {
+	tokens := []dotToken{}
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//") || (c == '#' && (i == 0 || src[i-1] == '\n')):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			tokens = append(tokens, dotToken{dotPunct, src[i : i+2], line, i})
+			i += 2
+		case strings.ContainsRune("{}[]=;,:", rune(c)):
+			tokens = append(tokens, dotToken{dotPunct, string(c), line, i})
+			i++
+		case c == '"':
+			start := i
+			i++
+			for i < len(src) && src[i] != '"' {
+				if src[i] == '\\' {
+					i++
+				}
+				if i < len(src) && src[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			i++
+			tokens = append(tokens, dotToken{dotID, src[start:i], line, start})
+		case c == '<':
+			start := i
+			depth := 0
+			for ; i < len(src); i++ {
+				if src[i] == '<' {
+					depth++
+				} else if src[i] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				} else if src[i] == '\n' {
+					line++
+				}
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated HTML string", line)
+			}
+			i++
+			html := src[start+1 : i-1]
+			if err := validateHTMLLabel(html); err != nil {
+				return nil, fmt.Errorf("line %d: invalid HTML label %q: %v", line, html, err)
+			}
+			tokens = append(tokens, dotToken{dotHTML, html, line, start})
+		case isDOTIDChar(rune(c)) && !unicode.IsDigit(rune(c)):
+			start := i
+			for i < len(src) && isDOTIDChar(rune(src[i])) {
+				i++
+			}
+			tokens = append(tokens, dotToken{dotID, src[start:i], line, start})
+		case c == '-' || c == '.' || unicode.IsDigit(rune(c)):
+			start := i
+			for i++; i < len(src) && (src[i] == '.' || unicode.IsDigit(rune(src[i]))); i++ {
+			}
+			tokens = append(tokens, dotToken{dotID, src[start:i], line, start})
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+	return append(tokens, dotToken{dotEOF, "", line, len(src)}), nil
+}
+
+// isDOTIDChar returns true for characters of unquoted IDs that aren't numerals (like -1.5), which can't start with a
+// digit. Note that '-' isn't one of them, so "n1->n2" is an edge.
+func isDOTIDChar(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c) || c >= 0x80
+}
+
+// validateHTMLLabel checks that an HTML-like label is well-formed XML, allowing HTML entities like &nbsp;
+func validateHTMLLabel(html string) error {
+	decoder := xml.NewDecoder(strings.NewReader("<label>" + html + "</label>"))
+	decoder.Strict = true
+	decoder.Entity = xml.HTMLEntity
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// dotParser checks the token stream against the DOT grammar (see https://graphviz.org/doc/info/lang.html)
+type dotParser struct {
+	tokens   []dotToken
+	pos      int
+	directed bool
+}
+
+func (p *dotParser) peek() dotToken {
+	return p.tokens[p.pos]
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.tokens[p.pos]
+	if t.kind != dotEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *dotParser) isPunct(value string) bool {
+	t := p.peek()
+	return t.kind == dotPunct && t.value == value
+}
+
+func (p *dotParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == dotID && strings.ToLower(t.value) == keyword
+}
+
+func (p *dotParser) isID() bool {
+	t := p.peek()
+	return t.kind == dotID || t.kind == dotHTML
+}
+
+func (p *dotParser) expectPunct(value string) error {
+	if !p.isPunct(value) {
+		return p.unexpected("'" + value + "'")
+	}
+	p.next()
+	return nil
+}
+
+func (p *dotParser) expectID() error {
+	if !p.isID() {
+		return p.unexpected("an ID")
+	}
+	p.next()
+	return nil
+}
+
+func (p *dotParser) unexpected(expected string) error {
+	t := p.peek()
+	if t.kind == dotEOF {
+		return fmt.Errorf("line %d: expected %s, but found the end of the input", t.line, expected)
+	}
+	return fmt.Errorf("line %d: expected %s, but found %q", t.line, expected, t.value)
+}
+
+func (p *dotParser) parseGraph() error {
+	if p.isKeyword("strict") {
+		p.next()
+	}
+	switch {
+	case p.isKeyword("digraph"):
+		p.directed = true
+	case p.isKeyword("graph"):
+		p.directed = false
+	default:
+		return p.unexpected("'graph' or 'digraph'")
+	}
+	p.next()
+	if p.isID() {
+		p.next()
+	}
+	if err := p.parseBlock(); err != nil {
+		return err
+	}
+	if p.peek().kind != dotEOF {
+		return p.unexpected("the end of the input")
+	}
+	return nil
+}
+
+// parseBlock parses '{' stmt_list '}'
+func (p *dotParser) parseBlock() error {
+	if err := p.expectPunct("{"); err != nil {
+		return err
+	}
+	for !p.isPunct("}") {
+		if err := p.parseStatement(); err != nil {
+			return err
+		}
+		if p.isPunct(";") {
+			p.next()
+		}
+	}
+	p.next()
+	return nil
+}
+
+func (p *dotParser) parseStatement() error {
+	if p.isKeyword("graph") || p.isKeyword("node") || p.isKeyword("edge") {
+		p.next()
+		return p.parseAttrList()
+	}
+	if p.isID() && p.tokens[p.pos+1].kind == dotPunct && p.tokens[p.pos+1].value == "=" {
+		p.next()
+		p.next()
+		return p.expectID()
+	}
+	if err := p.parseNodeOrSubgraph(); err != nil {
+		return err
+	}
+	for p.isPunct("->") || p.isPunct("--") {
+		if op := p.next().value; op != iff(p.directed, "->", "--") {
+			return fmt.Errorf("line %d: edge operator %s used in a %s", p.peek().line, op, iff(p.directed, "digraph", "graph"))
+		}
+		if err := p.parseNodeOrSubgraph(); err != nil {
+			return err
+		}
+	}
+	if p.isPunct("[") {
+		return p.parseAttrList()
+	}
+	return nil
+}
+
+func (p *dotParser) parseNodeOrSubgraph() error {
+	if p.isKeyword("subgraph") {
+		p.next()
+		if p.isID() {
+			p.next()
+		}
+		return p.parseBlock()
+	}
+	if p.isPunct("{") {
+		return p.parseBlock()
+	}
+	if err := p.expectID(); err != nil {
+		return err
+	}
+	// an optional port and compass point
+	for i := 0; i < 2 && p.isPunct(":"); i++ {
+		p.next()
+		if err := p.expectID(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseAttrList parses one or more '[' ID '=' ID [(';'|',')] ... ']'
+func (p *dotParser) parseAttrList() error {
+	if !p.isPunct("[") {
+		return p.unexpected("'['")
+	}
+	for p.isPunct("[") {
+		p.next()
+		for !p.isPunct("]") {
+			if err := p.expectID(); err != nil {
+				return err
+			}
+			if err := p.expectPunct("="); err != nil {
+				return err
+			}
+			if err := p.expectID(); err != nil {
+				return err
+			}
+			if p.isPunct(",") || p.isPunct(";") {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return nil
+}
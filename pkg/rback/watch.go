@@ -0,0 +1,58 @@
+package rback
+
+import (
+	"bytes"
+	"time"
+)
+
+// watcher keeps track of what -watch fetched from the cluster, to only render changes once they've settled
+type watcher struct {
+	fetched  []byte // by the last poll, if it succeeded
+	rendered []byte
+}
+
+// watch polls the RBAC resources of the cluster (of kubectl's current context) at the -watch interval and re-renders
+// them to the output file (or directory, with -split-by) whenever they change. Errors don't end watching: the resources
+// are simply fetched again with the next poll.
+func (r *Rback) watch() {
+	w := &watcher{}
+	for {
+		r.poll(w)
+		time.Sleep(r.config.watch)
+	}
+}
+
+// poll fetches the RBAC resources once, and renders them if they're unchanged since the last poll but differ from what
+// was rendered last, so that changes made together (e.g. by helm install) are rendered together. The first fetch is
+// rendered right away.
+func (r *Rback) poll(w *watcher) {
+	fetched, err := r.kubectlGet()
+	if err != nil {
+		warnf("Can't fetch the RBAC resources, retrying in %s: %v", r.config.watch, err)
+		w.fetched = nil
+		return
+	}
+	settled := w.rendered == nil || bytes.Equal(fetched, w.fetched)
+	w.fetched = fetched
+	if !settled || bytes.Equal(fetched, w.rendered) {
+		return
+	}
+	if err := r.render(fetched); err != nil {
+		warnf("Can't render the changed RBAC resources: %v", err)
+		return
+	}
+	debugf("Rendered the changed RBAC resources to %s", r.config.outputPath)
+	w.rendered = fetched
+}
+
+// render parses the fetched resources and writes the output, starting from scratch each time
+func (r *Rback) render(fetched []byte) error {
+	rendering := Rback{config: r.config}
+	if err := rendering.parseRBAC(bytes.NewReader(fetched)); err != nil {
+		return err
+	}
+	if r.config.splitBy != "" {
+		return rendering.writeSplit(r.config.outputPath)
+	}
+	return rendering.writeFile(r.config.outputPath)
+}
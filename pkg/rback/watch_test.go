@@ -0,0 +1,62 @@
+package rback
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWatchRendersSettledChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rback-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// stands in for kubectl, printing the RBAC resources of the "cluster", and failing like kubectl while there are none
+	input, kubectl, output := filepath.Join(dir, "rbac.json"), filepath.Join(dir, "kubectl"), filepath.Join(dir, "rbac.dot")
+	if err := ioutil.WriteFile(kubectl, []byte("#!/bin/sh\ncat "+input+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	setCluster := func(role string) {
+		t.Helper()
+		if role == "" {
+			os.Remove(input)
+			return
+		}
+		list := `{"kind": "List", "items": [{"kind": "ClusterRole", "metadata": {"name": "` + role + `"}}]}`
+		if err := ioutil.WriteFile(input, []byte(list), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &Rback{config: testConfig(t, "-quiet", "-kubectl", kubectl, "-watch", "1s", "-o", output)}
+	w := &watcher{}
+	steps := []struct {
+		cluster  string // the role in the cluster, if any
+		rendered string // the role in the output after polling
+	}{
+		{"first", "first"}, // rendered right away
+		{"first", "first"},
+		{"second", "first"}, // not rendered until it has settled
+		{"second", "second"},
+		{"", "second"}, // kubectl fails, which keeps the output
+		{"second", "second"},
+		{"third", "second"},
+		{"third", "third"},
+	}
+	for i, step := range steps {
+		setCluster(step.cluster)
+		r.poll(w)
+		rendered, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Fatalf("poll %d: %v", i+1, err)
+		}
+		for _, role := range []string{"first", "second", "third"} {
+			if strings.Contains(string(rendered), `"`+role+`"`) != (role == step.rendered) {
+				t.Errorf("poll %d: expected the output to show (only) role %s, got:\n%s", i+1, step.rendered, rendered)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package rback
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commandWhoAmI renders the permissions of a single identity, given as USER [GROUP...]. The kubectl plugin fills these
+// in from kubectl auth whoami, to show what the current user can do.
+const commandWhoAmI = "whoami"
+
+// kindIdentity is the internal kind selected by the whoami command
+const kindIdentity = "identity"
+
+// anonymousUser is the user of unauthenticated requests, which isn't in the system:authenticated group
+const anonymousUser = "system:anonymous"
+
+// serviceAccountUserPrefix is the prefix of the user names that ServiceAccounts authenticate as, followed by
+// NAMESPACE:NAME
+const serviceAccountUserPrefix = "system:serviceaccount:"
+
+// identity is a user as authenticated by the API server, along with its groups
+type identity struct {
+	user   string
+	groups []string
+}
+
+func (id identity) String() string {
+	if len(id.groups) == 0 {
+		return "User " + id.user
+	}
+	return fmt.Sprintf("User %s (Groups %s)", id.user, strings.Join(id.groups, ", "))
+}
+
+// includes returns true if bindings of the subject grant permissions to the identity: the subject is the user, one of
+// its groups, or a group that Kubernetes adds implicitly (system:authenticated, and the virtual ServiceAccount groups
+// for ServiceAccounts, which authenticate as system:serviceaccount:NAMESPACE:NAME)
+func (id identity) includes(subject KindNamespacedName) bool {
+	saNamespace, saName, isServiceAccount := id.serviceAccount()
+	switch subject.kind {
+	case "User":
+		return subject.name == id.user
+	case "ServiceAccount":
+		return isServiceAccount && subject.namespace == saNamespace && subject.name == saName
+	case "Group":
+		if contains(id.groups, subject.name) || (subject.name == "system:authenticated" && id.user != anonymousUser) {
+			return true
+		}
+		groupNs, ok := serviceAccountsGroupNamespace(subject)
+		return ok && isServiceAccount && (groupNs == "" || groupNs == saNamespace)
+	}
+	return false
+}
+
+// serviceAccount returns the namespace and name of the ServiceAccount the identity stands for, if any
+func (id identity) serviceAccount() (namespace, name string, ok bool) {
+	if !strings.HasPrefix(id.user, serviceAccountUserPrefix) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(id.user, serviceAccountUserPrefix), ":")
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// identityBound returns true if any binding grants the identity given to the whoami command permissions
+func (r *Rback) identityBound() bool {
+	for _, bindings := range r.permissions.RoleBindings {
+		for _, binding := range bindings {
+			if r.bindsIdentity(binding) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bindsIdentity returns true if any of the binding's subjects stands for the identity given to the whoami command
+func (r *Rback) bindsIdentity(binding Binding) bool {
+	for _, subject := range binding.subjects {
+		if r.config.identity.includes(subject) {
+			return true
+		}
+	}
+	return false
+}
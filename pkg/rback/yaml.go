@@ -0,0 +1,93 @@
+package rback
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// writeYAML writes the parsed (non-ignored) resources as a multi-document YAML stream, e.g. to archive exactly what
+// rback saw or to feed it back in later via -f
+func (r *Rback) writeYAML(w io.Writer) error {
+	objects := r.permissions.Objects
+	if len(objects) == 0 {
+		// an empty stream isn't valid input, but an empty List is
+		objects = []map[string]interface{}{{"apiVersion": "v1", "kind": "List", "items": []interface{}{}}}
+	}
+	for _, object := range objects {
+		document, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var yamlDocumentStart = regexp.MustCompile(`^(---|apiVersion:|kind:)`)
+
+// looksLikeYAML returns true for input that starts like a Kubernetes resource in YAML (as written by -format yaml or
+// kubectl get -o yaml), so that other input that isn't JSON, like an error message, is reported as invalid JSON
+func looksLikeYAML(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return yamlDocumentStart.MatchString(line)
+	}
+	return false
+}
+
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// yamlToJSON converts a stream of YAML documents, each a resource or a List, to JSON; several documents are converted
+// to a List of all their resources
+func yamlToJSON(data []byte) ([]byte, error) {
+	documents := []json.RawMessage{}
+	resources := []json.RawMessage{}
+	for i, document := range yamlDocumentSeparator.Split(string(data), -1) {
+		converted, err := yaml.YAMLToJSON([]byte(document))
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		if string(converted) == "null" {
+			continue // only comments, or nothing before the first separator
+		}
+		items, itemKind, err := kubeItems(converted)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		documents = append(documents, converted)
+		for _, item := range items {
+			if itemKind != "" {
+				// the items of lists of a single kind don't have a kind field, which they need in a List of mixed kinds
+				var object map[string]interface{}
+				if err := json.Unmarshal(item, &object); err != nil {
+					return nil, fmt.Errorf("document %d: %v", i+1, err)
+				}
+				object["kind"] = itemKind
+				if item, err = json.Marshal(object); err != nil {
+					return nil, err
+				}
+			}
+			resources = append(resources, item)
+		}
+	}
+	switch len(documents) {
+	case 0:
+		return nil, fmt.Errorf("it doesn't contain any documents")
+	case 1:
+		return documents[0], nil
+	}
+	return json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": resources})
+}
@@ -0,0 +1,107 @@
+package rback
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYAMLRoundTrip(t *testing.T) {
+	inputs, err := filepath.Glob("../../examples/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		config := testConfig(t, "-quiet", "-format", "yaml")
+		r := testRback(t, config, input)
+		var written bytes.Buffer
+		if err := r.writeYAML(&written); err != nil {
+			t.Fatal(err)
+		}
+
+		reloaded := &Rback{config: config}
+		if err := reloaded.parseRBAC(bytes.NewReader(written.Bytes())); err != nil {
+			t.Errorf("%s: can't read the YAML back: %v", input, err)
+			continue
+		}
+		var rewritten bytes.Buffer
+		if err := reloaded.writeYAML(&rewritten); err != nil {
+			t.Fatal(err)
+		}
+		if rewritten.String() != written.String() {
+			t.Errorf("%s: the YAML changed when read back:\n%s\nexpected:\n%s", input, rewritten.String(), written.String())
+		}
+		if !reflect.DeepEqual(reloaded.permissions.RoleBindings, r.permissions.RoleBindings) ||
+			!reflect.DeepEqual(reloaded.permissions.Roles, r.permissions.Roles) ||
+			!reflect.DeepEqual(reloaded.permissions.NamespaceLabels, r.permissions.NamespaceLabels) {
+			t.Errorf("%s: the bindings, roles or namespace labels changed when read back", input)
+		}
+	}
+}
+
+func TestYAMLInput(t *testing.T) {
+	// as written by kubectl get -o yaml, plus flow style, an anchor and a block scalar
+	r := testRback(t, testConfig(t, "-quiet"))
+	err := r.parseRBAC(strings.NewReader(`# fetched by kubectl
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata: {name: app, labels: {team: payments}}
+- apiVersion: rbac.authorization.k8s.io/v1
+  kind: Role
+  metadata:
+    name: reader
+    namespace: app
+    annotations:
+      note: |
+        reads: pods
+  rules:
+  - &read {apiGroups: [""], resources: [pods], verbs: [get, list]}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: reader
+  namespace: app
+roleRef: {kind: Role, name: reader}
+subjects:
+- kind: ServiceAccount
+  name: worker
+  namespace: app
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := r.permissions.NamespaceLabels["app"]; labels["team"] != "payments" {
+		t.Errorf("expected the labels of namespace app, got %v", labels)
+	}
+	if rules := r.permissions.Roles["app"]["reader"].rules; len(rules) != 1 || rules[0].toHumanReadableString() != "get,list pods" {
+		t.Errorf("expected Role app/reader to grant get,list pods, got %v", rules)
+	}
+	if binding := r.permissions.RoleBindings["app"]["reader"]; binding.role != (NamespacedName{"app", "reader"}) {
+		t.Errorf("expected RoleBinding app/reader to reference Role app/reader, got %v", binding.role)
+	}
+}
+
+func TestInvalidInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// what kubectl prints instead of JSON or YAML is reported as invalid JSON, even though it's valid YAML
+		{"error: You must be logged in to the server (Unauthorized)\n", "Input is not valid JSON"},
+		{"<html>Bad Gateway</html>", "Input is not valid JSON"},
+		{`{"kind": "List", "items": [}`, "Input is not valid JSON"},
+		{"apiVersion: v1\nkind: List\n items: []\n", "Input is not valid YAML"},
+		{"---\nkind: Role\n---\nname: x\n", "Input is not valid YAML (document 3: Expected kind=List, but found \"\")"},
+	}
+	for _, test := range tests {
+		if _, _, err := kubeItems([]byte(test.input)); err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("kubeItems(%q): expected an error starting with %q, got %v", test.input, test.expected, err)
+		}
+	}
+}